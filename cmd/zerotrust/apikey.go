@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/apikey"
+)
+
+func runAPIKey(args []string) int {
+	// parse command line flags
+	flags := flag.NewFlagSet("apikey", flag.ExitOnError)
+	algorithm := flags.String("algorithm", "argon2id", "hash algorithm, argon2id or sha256")
+	prefix := flags.String("prefix", apikey.DefaultPrefix, "key id prefix, must match api_keys.prefix")
+	owner := flags.String("owner", "", "user id the key acts on behalf of")
+	scopes := flags.String("scopes", "", "comma separated scopes")
+	_ = flags.Parse(args)
+	if *owner == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "apikey: -owner is required")
+		flags.Usage()
+		return 2
+	}
+
+	// generate key, only the hash is meant to be stored
+	presented, hash, err := apikey.Generate(*prefix, *algorithm)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "apikey: %v\n", err)
+		return 1
+	}
+	id, _, _ := strings.Cut(presented, ".")
+
+	_, _ = fmt.Fprintf(os.Stdout, "key: %s\n\n", presented)
+	_, _ = fmt.Fprintf(os.Stdout, "  - id: %q\n    hash: %q\n    owner: %q\n", id, hash, *owner)
+	if *scopes != "" {
+		quoted := make([]string, 0)
+		for _, scope := range strings.Split(*scopes, ",") {
+			quoted = append(quoted, fmt.Sprintf("%q", strings.TrimSpace(scope)))
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "    scopes: [ %s ]\n", strings.Join(quoted, ", "))
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/jwt"
+)
+
+func runJWT(args []string) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: zerotrust jwt keygen|sign [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "keygen":
+		return runJWTKeygen(args[1:])
+	case "sign":
+		return runJWTSign(args[1:])
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: unknown command %q\n", args[0])
+		return 2
+	}
+}
+
+func runJWTKeygen(args []string) int {
+	// parse command line flags
+	flags := flag.NewFlagSet("jwt keygen", flag.ExitOnError)
+	alg := flags.String("alg", "ES256", "key algorithm, ES256, ES384 or EdDSA")
+	kid := flags.String("kid", "", "key id, defaults to the current date")
+	dir := flags.String("dir", ".", "directory to write the private key to")
+	_ = flags.Parse(args)
+	if *kid == "" {
+		*kid = time.Now().Format("20060102")
+	}
+
+	// generate key and write it as <kid>.pem
+	key, err := jwt.GenerateKey(*alg)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: %v\n", err)
+		return 1
+	}
+	data, err := jwt.EncodePrivateKey(key)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: %v\n", err)
+		return 1
+	}
+	path := filepath.Join(*dir, *kid+".pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: %v\n", err)
+		return 1
+	}
+
+	// print public key set for verifiers
+	jwk, err := jwt.NewJWK(key.Public(), *kid, *alg)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: %v\n", err)
+		return 1
+	}
+	out, _ := json.MarshalIndent(jwt.JWKS{Keys: []jwt.JWK{*jwk}}, "", "  ")
+	_, _ = fmt.Fprintln(os.Stdout, string(out))
+	return 0
+}
+
+func runJWTSign(args []string) int {
+	// parse command line flags
+	flags := flag.NewFlagSet("jwt sign", flag.ExitOnError)
+	keyPath := flags.String("key", "", "path to pem private key")
+	kid := flags.String("kid", "", "key id, defaults to the key file name")
+	claimsJSON := flags.String("claims", "{}", "claims as json")
+	ttl := flags.Duration("ttl", 5*time.Minute, "token lifetime")
+	_ = flags.Parse(args)
+	if *keyPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "jwt: -key is required")
+		return 2
+	}
+	if *kid == "" {
+		*kid = trimExt(filepath.Base(*keyPath))
+	}
+
+	// load key and claims
+	key, err := jwt.LoadPrivateKey(*keyPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: %v\n", err)
+		return 1
+	}
+	claims := jwt.Claims{}
+	if err := json.Unmarshal([]byte(*claimsJSON), &claims); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: invalid claims: %v\n", err)
+		return 1
+	}
+
+	// fill time claims unless given
+	now := time.Now()
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = now.Add(*ttl).Unix()
+	}
+
+	token, err := jwt.Sign(claims, key, *kid)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "jwt: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintln(os.Stdout, token)
+	return 0
+}
+
+func trimExt(name string) string {
+	return name[:len(name)-len(filepath.Ext(name))]
+}
@@ -3,17 +3,34 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/ovinc/zerotrust/internal/admin"
+	"github.com/ovinc/zerotrust/internal/anomaly"
+	"github.com/ovinc/zerotrust/internal/apikey"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/assertion"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/binding"
+	"github.com/ovinc/zerotrust/internal/clientip"
 	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/drftoken"
+	"github.com/ovinc/zerotrust/internal/geoip"
 	"github.com/ovinc/zerotrust/internal/handler"
+	"github.com/ovinc/zerotrust/internal/ipfilter"
 	"github.com/ovinc/zerotrust/internal/log"
 	"github.com/ovinc/zerotrust/internal/otel"
-	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/ovinc/zerotrust/internal/ratelimit"
+	"github.com/ovinc/zerotrust/internal/sessionlimit"
+	"github.com/ovinc/zerotrust/internal/sessiontouch"
+	"github.com/ovinc/zerotrust/internal/stepup"
+	"github.com/ovinc/zerotrust/internal/usersource"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,17 +38,69 @@ func main() {
 	// initialize logger with trace hook
 	log.Init()
 
+	// dispatch sub commands, default to serving
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		case "apikey":
+			os.Exit(runAPIKey(os.Args[2:]))
+		case "jwt":
+			os.Exit(runJWT(os.Args[2:]))
+		case "serve":
+			serve(os.Args[2:])
+			return
+		}
+	}
+	serve(os.Args[1:])
+}
+
+func serve(args []string) {
+	// parse command line flags
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", "configs/config.yaml", "path to config file")
+	_ = flags.Parse(args)
+
+	// load config, compile policy and validate authenticators
+	config.Init(*configPath)
+	policy.Init()
+	clientip.Init()
+	ipfilter.Init()
+	geoip.Init()
+	ratelimit.Init()
+	apikey.Init()
+	auth.Init()
+	binding.Init()
+	anomaly.Init()
+	sessionlimit.Init()
+	sessiontouch.Init()
+	stepup.Init()
+	assertion.Init()
+	admin.Init()
+
 	// initialize opentelemetry
+	otel.Init()
 	defer otel.Shutdown(context.Background())
 
-	// initialize store
-	defer store.Close()
+	// initialize application profiles and their stores
+	app.Init()
+	defer app.Close()
+	drftoken.Init()
+	defer drftoken.Close()
+	usersource.Init()
+	defer usersource.Close()
 
 	// setup http routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/verify", handler.VerifyHandler)
 	mux.HandleFunc("/forward-auth", handler.ForwardAuthHandler)
 	mux.HandleFunc("/health", handler.HealthHandler)
+	mux.HandleFunc("/.well-known/jwks.json", handler.JWKSHandler)
+	mux.HandleFunc("/token", handler.TokenHandler)
+	mux.HandleFunc("/whoami", handler.WhoamiHandler)
+	mux.HandleFunc("/logout", handler.LogoutHandler)
 
 	// create http server with timeouts
 	cfg := config.Get()
@@ -43,15 +112,45 @@ func main() {
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	tlsConfig, err := serverTLSConfig(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid server.tls")
+	}
+	server.TLSConfig = tlsConfig
 
-	// start server in goroutine
+	// start server in goroutine, serve tls when a certificate is configured
 	go func() {
 		logrus.Infof("starting server on %s", addr)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logrus.WithError(err).Fatal("server error")
 		}
 	}()
 
+	// admin api listens separately so it can stay off the public network
+	var adminServer *http.Server
+	if cfg.Admin.Enabled {
+		adminAddr := fmt.Sprintf("%s:%d", cfg.Admin.Host, cfg.Admin.Port)
+		adminServer = &http.Server{
+			Addr:         adminAddr,
+			Handler:      otel.Middleware(admin.Handler()),
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		}
+		go func() {
+			logrus.Infof("starting admin server on %s", adminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logrus.WithError(err).Fatal("admin server error")
+			}
+		}()
+	}
+
 	// wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -62,6 +161,11 @@ func main() {
 	if err := server.Shutdown(context.Background()); err != nil {
 		logrus.WithError(err).Error("server forced to shutdown")
 	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(context.Background()); err != nil {
+			logrus.WithError(err).Error("admin server forced to shutdown")
+		}
+	}
 
 	logrus.Info("server stopped")
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ovinc/zerotrust/internal/anomaly"
+	"github.com/ovinc/zerotrust/internal/apikey"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/binding"
+	"github.com/ovinc/zerotrust/internal/clientip"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/geoip"
+	"github.com/ovinc/zerotrust/internal/ipfilter"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/ovinc/zerotrust/internal/ratelimit"
+	"github.com/ovinc/zerotrust/internal/replay"
+	"github.com/ovinc/zerotrust/internal/sessionlimit"
+	"github.com/ovinc/zerotrust/internal/sessiontouch"
+	"github.com/ovinc/zerotrust/internal/stepup"
+)
+
+func runReplay(args []string) int {
+	// parse command line flags
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", "configs/config.yaml", "path to candidate config file")
+	logPath := flags.String("log", "", "path to decision log in json lines")
+	_ = flags.Parse(args)
+	if *logPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "replay: -log is required")
+		flags.Usage()
+		return 2
+	}
+
+	// load candidate config and stub the session store, recorded traffic is not rate limited again
+	config.Init(*configPath)
+	policy.Init()
+	clientip.Init()
+	ipfilter.Init()
+	geoip.Init()
+	apikey.Init()
+	auth.Init()
+	binding.Init()
+	anomaly.Init()
+	sessionlimit.Init()
+	sessiontouch.Init()
+	stepup.Init()
+	ratelimit.DisableQuotas()
+	server, err := replay.StartMemory()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "replay: failed to start session store stub: %v\n", err)
+		return 1
+	}
+	defer server.Close()
+	defer app.Close()
+
+	// replay recorded decisions against the candidate config
+	report, err := replay.RunFile(context.Background(), server, *logPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+	report.Print(os.Stdout)
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ovinc/zerotrust/internal/anomaly"
+	"github.com/ovinc/zerotrust/internal/apikey"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/assertion"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/binding"
+	"github.com/ovinc/zerotrust/internal/clientip"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/geoip"
+	"github.com/ovinc/zerotrust/internal/ipfilter"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/ovinc/zerotrust/internal/replay"
+	"github.com/ovinc/zerotrust/internal/sessionlimit"
+	"github.com/ovinc/zerotrust/internal/sessiontouch"
+	"github.com/ovinc/zerotrust/internal/stepup"
+	"github.com/ovinc/zerotrust/internal/testrunner"
+)
+
+func runTest(args []string) int {
+	// parse command line flags
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	configPath := flags.String("config", "configs/config.yaml", "path to config file")
+	casesPath := flags.String("cases", "", "path to yaml file with test cases")
+	_ = flags.Parse(args)
+	if *casesPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "test: -cases is required")
+		flags.Usage()
+		return 2
+	}
+
+	// load config and cases, then stub the session store
+	config.Init(*configPath)
+	policy.Init()
+	clientip.Init()
+	ipfilter.Init()
+	geoip.Init()
+	apikey.Init()
+	auth.Init()
+	binding.Init()
+	anomaly.Init()
+	sessionlimit.Init()
+	sessiontouch.Init()
+	stepup.Init()
+	assertion.Init()
+	suite, err := testrunner.LoadSuite(*casesPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "test: failed to load cases: %v\n", err)
+		return 1
+	}
+	server, err := replay.StartMemory()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "test: failed to start session store stub: %v\n", err)
+		return 1
+	}
+	defer server.Close()
+	defer app.Close()
+
+	// run cases and report
+	results, err := testrunner.Run(context.Background(), server, suite)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "test: %v\n", err)
+		return 1
+	}
+	if failed := testrunner.Print(os.Stdout, results); failed > 0 {
+		return 1
+	}
+	return 0
+}
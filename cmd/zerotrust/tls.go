@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/ovinc/zerotrust/internal/clientcert"
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func serverTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.Server.TLS.CertFile == "" {
+		return nil, nil
+	}
+
+	// map client auth mode, client certificates are always verified when sent
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch cfg.Server.TLS.ClientAuth {
+	case "", "none":
+		return tlsConfig, nil
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client_auth %q", cfg.Server.TLS.ClientAuth)
+	}
+
+	// client cas default to every mtls pool
+	files := cfg.Server.TLS.ClientCAFiles
+	if len(files) == 0 {
+		for _, pool := range cfg.MTLS.Pools {
+			files = append(files, pool.CAFiles...)
+		}
+	}
+	pool, err := clientcert.LoadCertPool(files)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/apikey"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/ratelimit"
+	"github.com/ovinc/zerotrust/internal/sessionlimit"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultScope = "zerotrust:admin"
+
+const (
+	defaultFailureLimit  = 5
+	defaultFailureWindow = 10 * time.Minute
+)
+
+var limiter *ratelimit.Limiter
+
+type actorKey struct{}
+
+type actor struct {
+	owner string
+	keyID string
+}
+
+func Init() {
+	cfg := config.Get().Admin
+	if !cfg.Enabled {
+		return
+	}
+
+	// admin callers authenticate with api keys carrying the admin scope
+	if !config.Get().APIKey.Enabled {
+		logrus.Fatal("admin api requires api_keys to be enabled")
+	}
+	if err := initAudit(cfg.AuditLog); err != nil {
+		logrus.WithError(err).Fatal("failed to open audit log")
+	}
+	decisions = newDecisionRing(cfg.RecentDecisions)
+
+	// own failure limit, independent of rate_limit and its public buckets
+	if cfg.Failures.Limit == 0 {
+		cfg.Failures.Limit = defaultFailureLimit
+	}
+	if cfg.Failures.Window <= 0 {
+		cfg.Failures.Window = defaultFailureWindow
+	}
+	limiter = ratelimit.NewLimiter("admin_failures", cfg.Failures)
+}
+
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/sessions/{id}", getSession)
+	mux.HandleFunc("DELETE /admin/sessions/{id}", revokeSession)
+	mux.HandleFunc("GET /admin/users/{user_id}/sessions", listUserSessions)
+	mux.HandleFunc("DELETE /admin/users/{user_id}/sessions", revokeUserSessions)
+	if sessionlimit.Enabled() {
+		mux.HandleFunc("GET /admin/users/{user_id}/active_sessions", listActiveSessions)
+	}
+	mux.HandleFunc("GET /admin/decisions", listDecisions)
+	return authenticate(mux)
+}
+
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := config.Get().Admin.Scope
+		if scope == "" {
+			scope = defaultScope
+		}
+
+		// callers that failed too often are turned away before any key is hashed
+		clientIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if retryAfter, ok := limiter.Allow(r.Context(), clientIP); !ok {
+			w.Header().Set("Retry-After", ratelimit.Seconds(retryAfter))
+			writeJSON(w, http.StatusTooManyRequests, "too many requests", nil)
+			return
+		}
+
+		// api key with the admin scope, every failure is audited
+		key, err := apikey.Verify(r.Context(), apikey.FromHeaders(r.Header.Get("Authorization"), ""))
+		if err != nil {
+			limiter.Fail(r.Context(), clientIP)
+			auditLog(r, "auth.denied", logrus.Fields{"path": r.URL.Path, "error": err.Error()})
+			writeJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+			return
+		}
+		if !slices.Contains(key.Scopes, scope) {
+			auditLog(r, "auth.denied", logrus.Fields{"path": r.URL.Path, "key_id": key.ID, "error": "missing admin scope"})
+			writeJSON(w, http.StatusForbidden, "forbidden", nil)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), actorKey{}, &actor{owner: key.Owner, keyID: key.ID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, message string, data interface{}) {
+	// same envelope as the public endpoints
+	var errValue interface{}
+	if status >= http.StatusBadRequest {
+		errValue = message
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": status, "error": errValue, "message": message, "data": data})
+}
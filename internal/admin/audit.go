@@ -0,0 +1,51 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/ovinc/zerotrust/internal/log"
+	"github.com/sirupsen/logrus"
+)
+
+var audit *logrus.Logger
+
+func initAudit(path string) error {
+	// same json format as the main log, optionally in its own file
+	audit = logrus.New()
+	audit.SetFormatter(logrus.StandardLogger().Formatter)
+	audit.AddHook(&log.TraceHook{})
+	audit.SetOutput(os.Stdout)
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		audit.SetOutput(f)
+	}
+	return nil
+}
+
+func auditLog(r *http.Request, action string, fields logrus.Fields) {
+	entry := audit.WithContext(r.Context()).WithFields(fields).WithFields(logrus.Fields{
+		"audit":       true,
+		"action":      action,
+		"remote_addr": r.RemoteAddr,
+	})
+	if actor, ok := r.Context().Value(actorKey{}).(*actor); ok {
+		entry = entry.WithFields(logrus.Fields{"actor": actor.owner, "key_id": actor.keyID})
+	}
+	entry.Info("admin action")
+}
+
+func Event(ctx context.Context, action string, fields logrus.Fields) {
+	logger := audit
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	logger.WithContext(ctx).WithFields(fields).WithFields(logrus.Fields{
+		"audit":  true,
+		"action": action,
+	}).Warn("security event")
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultRecentDecisions = 500
+
+type DecisionEntry struct {
+	Time          time.Time `json:"time"`
+	ClientIP      string    `json:"client_ip"`
+	Country       string    `json:"country,omitempty"`
+	ASN           uint      `json:"asn,omitempty"`
+	Method        string    `json:"method"`
+	Host          string    `json:"host"`
+	Path          string    `json:"path"`
+	RequestID     string    `json:"request_id,omitempty"`
+	Status        int       `json:"status"`
+	Result        string    `json:"result"`
+	Reason        string    `json:"reason,omitempty"`
+	Application   string    `json:"application"`
+	Route         string    `json:"route,omitempty"`
+	Authenticator string    `json:"authenticator,omitempty"`
+	UserID        string    `json:"user_id,omitempty"`
+	SessionID     string    `json:"session_id,omitempty"`
+	CredentialID  string    `json:"credential_id,omitempty"`
+	Tenant        string    `json:"tenant,omitempty"`
+	Rule          string    `json:"rule,omitempty"`
+}
+
+type decisionRing struct {
+	mu      sync.Mutex
+	entries []DecisionEntry
+	next    int
+	full    bool
+}
+
+var decisions *decisionRing
+
+func newDecisionRing(size int) *decisionRing {
+	if size <= 0 {
+		size = defaultRecentDecisions
+	}
+	return &decisionRing{entries: make([]DecisionEntry, size)}
+}
+
+func Record(entry DecisionEntry) {
+	if decisions == nil {
+		return
+	}
+	decisions.mu.Lock()
+	defer decisions.mu.Unlock()
+	decisions.entries[decisions.next] = entry
+	decisions.next = (decisions.next + 1) % len(decisions.entries)
+	if decisions.next == 0 {
+		decisions.full = true
+	}
+}
+
+func (d *decisionRing) recent(limit int, keep func(*DecisionEntry) bool) []DecisionEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// walk backwards from the newest entry
+	count := d.next
+	if d.full {
+		count = len(d.entries)
+	}
+	result := make([]DecisionEntry, 0, min(limit, count))
+	for i := 0; i < count && len(result) < limit; i++ {
+		entry := &d.entries[(d.next-1-i+len(d.entries))%len(d.entries)]
+		if keep(entry) {
+			result = append(result, *entry)
+		}
+	}
+	return result
+}
@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/sessionlimit"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+type sessionInfo struct {
+	Application string              `json:"application"`
+	SessionID   string              `json:"session_id"`
+	UserID      string              `json:"user_id"`
+	Backend     string              `json:"backend"`
+	Attributes  map[string][]string `json:"attributes"`
+	ExpiresAt   *time.Time          `json:"expires_at"`
+}
+
+var errUnknownApplication = errors.New("unknown application")
+
+func applications(r *http.Request) ([]*app.Application, error) {
+	// one profile when named, else every profile
+	name := r.URL.Query().Get("application")
+	if name == "" {
+		return app.All(), nil
+	}
+	if a := app.Lookup(name); a != nil {
+		return []*app.Application{a}, nil
+	}
+	return nil, errUnknownApplication
+}
+
+func decodeSession(r *http.Request, a *app.Application, sessionID string) (*sessionInfo, error) {
+	ctx := r.Context()
+	data, err := a.Store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	userInfo, err := a.Decoder.Decode(ctx, []byte(data))
+	if err != nil {
+		return nil, err
+	}
+	info := &sessionInfo{
+		Application: a.Name,
+		SessionID:   sessionID,
+		UserID:      userInfo.UserID,
+		Backend:     userInfo.Backend,
+		Attributes:  userInfo.Attributes,
+	}
+	if ttl, err := a.Store.SessionTTL(ctx, sessionID); err == nil && ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UTC()
+		info.ExpiresAt = &expiresAt
+	}
+	return info, nil
+}
+
+func findSession(r *http.Request, sessionID string) (*sessionInfo, *app.Application, error) {
+	profiles, err := applications(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// first profile holding the session wins
+	for _, a := range profiles {
+		info, err := decodeSession(r, a, sessionID)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		return info, a, err
+	}
+	return nil, nil, redis.Nil
+}
+
+func userSessions(r *http.Request, userID string) ([]*sessionInfo, []*app.Application, error) {
+	profiles, err := applications(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// scan every session key and keep the ones of the user
+	sessions := []*sessionInfo{}
+	var owners []*app.Application
+	for _, a := range profiles {
+		err := a.Store.ScanSessions(r.Context(), func(sessionID string) error {
+			info, err := decodeSession(r, a, sessionID)
+			if err != nil {
+				// expired meanwhile or not a django session
+				return nil
+			}
+			if info.UserID == userID {
+				sessions = append(sessions, info)
+				owners = append(owners, a)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return sessions, owners, nil
+}
+
+func getSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	info, _, err := findSession(r, sessionID)
+	auditLog(r, "session.get", logrus.Fields{"session_id": auth.MaskSecret(sessionID), "found": err == nil})
+	if !writeError(w, err) {
+		writeJSON(w, http.StatusOK, "ok", info)
+	}
+}
+
+func revokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	info, a, err := findSession(r, sessionID)
+	if err == nil {
+		err = a.RevokeSession(r.Context(), sessionID)
+	}
+	fields := logrus.Fields{"session_id": auth.MaskSecret(sessionID), "revoked": err == nil}
+	if info != nil {
+		fields["application"] = info.Application
+		fields["user_id"] = info.UserID
+	}
+	auditLog(r, "session.revoke", fields)
+	if !writeError(w, err) {
+		writeJSON(w, http.StatusOK, "ok", info)
+	}
+}
+
+func listUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+	sessions, _, err := userSessions(r, userID)
+	auditLog(r, "user.sessions.list", logrus.Fields{"user_id": userID, "count": len(sessions)})
+	if !writeError(w, err) {
+		writeJSON(w, http.StatusOK, "ok", sessions)
+	}
+}
+
+type activeSession struct {
+	Application string `json:"application"`
+	sessionlimit.Session
+}
+
+func listActiveSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+	profiles, err := applications(r)
+
+	// tracked sessions of the user in every profile, least recently used first
+	sessions := []activeSession{}
+	for i := 0; err == nil && i < len(profiles); i++ {
+		var active []sessionlimit.Session
+		active, err = sessionlimit.Active(r.Context(), profiles[i], userID)
+		for _, session := range active {
+			sessions = append(sessions, activeSession{Application: profiles[i].Name, Session: session})
+		}
+	}
+	auditLog(r, "user.active_sessions.list", logrus.Fields{"user_id": userID, "count": len(sessions)})
+	if !writeError(w, err) {
+		writeJSON(w, http.StatusOK, "ok", sessions)
+	}
+}
+
+func revokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+	sessions, owners, err := userSessions(r, userID)
+	revoked := make([]*sessionInfo, 0, len(sessions))
+	for i := 0; err == nil && i < len(sessions); i++ {
+		if err = owners[i].RevokeSession(r.Context(), sessions[i].SessionID); err == nil {
+			revoked = append(revoked, sessions[i])
+		}
+	}
+	auditLog(r, "user.sessions.revoke", logrus.Fields{"user_id": userID, "count": len(revoked)})
+	if !writeError(w, err) {
+		writeJSON(w, http.StatusOK, "ok", revoked)
+	}
+}
+
+func listDecisions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	userID, reason := query.Get("user_id"), query.Get("reason")
+	status, _ := strconv.Atoi(query.Get("status"))
+
+	entries := decisions.recent(limit, func(e *DecisionEntry) bool {
+		return (userID == "" || e.UserID == userID) &&
+			(reason == "" || e.Reason == reason) &&
+			(status == 0 || e.Status == status)
+	})
+	auditLog(r, "decisions.list", logrus.Fields{"count": len(entries)})
+	writeJSON(w, http.StatusOK, "ok", entries)
+}
+
+func writeError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, redis.Nil):
+		writeJSON(w, http.StatusNotFound, "not_found", nil)
+	case errors.Is(err, errUnknownApplication):
+		writeJSON(w, http.StatusBadRequest, "unknown_application", nil)
+	default:
+		logrus.WithError(err).Error("admin request failed")
+		writeJSON(w, http.StatusInternalServerError, "internal_server_error", nil)
+	}
+	return true
+}
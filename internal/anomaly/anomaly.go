@@ -0,0 +1,176 @@
+package anomaly
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/geoip"
+	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonReauth = "anomaly_reauth"
+
+const (
+	KindImpossibleTravel = "impossible_travel"
+	KindConcurrentIPs    = "concurrent_ips"
+)
+
+const (
+	ActionFlag   = "flag"
+	ActionReauth = "reauth"
+)
+
+const (
+	defaultKeyFormat     = "zerotrust:anomaly:{user_id}"
+	defaultWindow        = time.Hour
+	defaultMaxSpeedKMH   = 1000
+	defaultMinDistanceKM = 300
+)
+
+const earthRadiusKM = 6371
+
+type Anomaly struct {
+	Kind       string
+	PreviousIP string
+	DistanceKM float64
+	SpeedKMH   float64
+	IPs        int
+	RecentIPs  []string
+}
+
+var cfg config.AnomalyConfig
+
+func Init() {
+	cfg = config.Get().Anomaly
+	if !cfg.Enabled {
+		return
+	}
+
+	// fill defaults
+	if cfg.KeyFormat == "" {
+		cfg.KeyFormat = defaultKeyFormat
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MaxSpeedKMH <= 0 {
+		cfg.MaxSpeedKMH = defaultMaxSpeedKMH
+	}
+	if cfg.MinDistanceKM <= 0 {
+		cfg.MinDistanceKM = defaultMinDistanceKM
+	}
+	if cfg.Action == "" {
+		cfg.Action = ActionFlag
+	}
+
+	// validate
+	if cfg.Action != ActionFlag && cfg.Action != ActionReauth {
+		logrus.WithField("action", cfg.Action).Fatal("anomaly.action must be flag or reauth")
+	}
+	if !geoip.Enabled() {
+		logrus.Warn("anomaly detection without geoip only checks concurrent ips")
+	}
+}
+
+func Enabled() bool {
+	return cfg.Enabled
+}
+
+func Reauth() bool {
+	return cfg.Action == ActionReauth
+}
+
+func Check(ctx context.Context, s *store.Store, userID, sessionID, ip string, location *geoip.Location) (*Anomaly, error) {
+	now := time.Now()
+	recent, err := s.Window(ctx, key(userID), ip, now, cfg.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	// sessions used within the window are the ones to revoke on reauth
+	if _, err := s.Window(ctx, sessionsKey(userID), sessionID, now, cfg.Window); err != nil {
+		return nil, err
+	}
+
+	// far away ips used shortly before, speed is measured from when each was last seen
+	ips := []string{ip}
+	var travel *Anomaly
+	for _, z := range recent {
+		previousIP, _ := z.Member.(string)
+		if previousIP == ip {
+			continue
+		}
+		ips = append(ips, previousIP)
+		if location == nil || !location.Coordinates || travel != nil {
+			continue
+		}
+		previous := geoip.Lookup(previousIP)
+		if previous == nil || !previous.Coordinates {
+			continue
+		}
+		distance := haversine(location, previous)
+		if distance < cfg.MinDistanceKM {
+			continue
+		}
+		hours := now.Sub(time.UnixMilli(int64(z.Score))).Hours()
+		speed := math.Inf(1)
+		if hours > 0 {
+			speed = distance / hours
+		}
+		if speed > cfg.MaxSpeedKMH {
+			travel = &Anomaly{Kind: KindImpossibleTravel, PreviousIP: previousIP, DistanceKM: math.Round(distance), SpeedKMH: math.Round(speed)}
+		}
+	}
+	if travel != nil {
+		travel.IPs = len(ips)
+		travel.RecentIPs = ips
+		return travel, nil
+	}
+	if cfg.MaxIPs > 0 && len(ips) > cfg.MaxIPs {
+		return &Anomaly{Kind: KindConcurrentIPs, IPs: len(ips), RecentIPs: ips}, nil
+	}
+	return nil, nil
+}
+
+func Revoke(ctx context.Context, a *app.Application, userID, sessionID string) ([]string, error) {
+	// the alert may come from the real user, so every session of the window goes, not only this one
+	sessionIDs := []string{sessionID}
+	members, err := a.Store.Members(ctx, sessionsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range members {
+		if member, _ := z.Member.(string); member != "" && member != sessionID {
+			sessionIDs = append(sessionIDs, member)
+		}
+	}
+	for i, id := range sessionIDs {
+		if err := a.RevokeSession(ctx, id); err != nil {
+			return sessionIDs[:i], err
+		}
+	}
+
+	// with no session left the window starts over, the audit log keeps the ips
+	return sessionIDs, a.Store.Delete(ctx, key(userID), sessionsKey(userID))
+}
+
+func key(userID string) string {
+	return strings.ReplaceAll(cfg.KeyFormat, "{user_id}", userID)
+}
+
+func sessionsKey(userID string) string {
+	return key(userID) + ":sessions"
+}
+
+func haversine(a, b *geoip.Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKM * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,97 @@
+package anomaly
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/geoip"
+	"github.com/ovinc/zerotrust/internal/store"
+)
+
+const anomalyConfig = `
+geoip:
+  enabled: true
+  country_database: "../geoip/testdata/GeoLite2-City-Test.mmdb"
+anomaly:
+  enabled: true
+  max_ips: 2
+`
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name            string
+		a, b            geoip.Location
+		want, tolerance float64
+	}{
+		{name: "same point", a: geoip.Location{Latitude: 51.5, Longitude: -0.12}, b: geoip.Location{Latitude: 51.5, Longitude: -0.12}, want: 0, tolerance: 0.001},
+		{name: "london paris", a: geoip.Location{Latitude: 51.5074, Longitude: -0.1278}, b: geoip.Location{Latitude: 48.8566, Longitude: 2.3522}, want: 344, tolerance: 1},
+		{name: "new york los angeles", a: geoip.Location{Latitude: 40.7128, Longitude: -74.0060}, b: geoip.Location{Latitude: 34.0522, Longitude: -118.2437}, want: 3936, tolerance: 2},
+		{name: "across the date line", a: geoip.Location{Latitude: 0, Longitude: 179.5}, b: geoip.Location{Latitude: 0, Longitude: -179.5}, want: 111, tolerance: 1},
+		{name: "antipodes", a: geoip.Location{Latitude: 0, Longitude: 0}, b: geoip.Location{Latitude: 0, Longitude: 180}, want: math.Pi * earthRadiusKM, tolerance: 0.001},
+		{name: "poles", a: geoip.Location{Latitude: 90}, b: geoip.Location{Latitude: -90}, want: math.Pi * earthRadiusKM, tolerance: 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversine(&tt.a, &tt.b)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Fatalf("haversine() = %.1f, want %.1f", got, tt.want)
+			}
+			if back := haversine(&tt.b, &tt.a); math.Abs(back-got) > 1e-9 {
+				t.Fatalf("haversine() is not symmetric: %f and %f", got, back)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(anomalyConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.Init(path)
+	geoip.Init()
+	Init()
+
+	server := miniredis.RunT(t)
+	port, _ := strconv.Atoi(server.Port())
+	ctx := context.Background()
+	s, err := store.New(ctx, &config.RedisConfig{Host: server.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// london then linköping seconds later is faster than any flight
+	if found, err := Check(ctx, s, "7", "a", "81.2.69.142", geoip.Lookup("81.2.69.142")); found != nil || err != nil {
+		t.Fatalf("first request: %+v, %v", found, err)
+	}
+	found, err := Check(ctx, s, "7", "b", "89.160.20.112", geoip.Lookup("89.160.20.112"))
+	if err != nil || found == nil || found.Kind != KindImpossibleTravel || found.PreviousIP != "81.2.69.142" {
+		t.Fatalf("travel: %+v, %v", found, err)
+	}
+	if found.DistanceKM < 1200 || found.DistanceKM > 1300 {
+		t.Fatalf("distance = %v km", found.DistanceKM)
+	}
+
+	// ips without coordinates only count towards max_ips
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if found, err := Check(ctx, s, "8", "c", ip, geoip.Lookup(ip)); found != nil || err != nil {
+			t.Fatalf("request %d: %+v, %v", i, found, err)
+		}
+	}
+	found, err = Check(ctx, s, "8", "c", "10.0.0.3", geoip.Lookup("10.0.0.3"))
+	if err != nil || found == nil || found.Kind != KindConcurrentIPs || found.IPs != 3 || len(found.RecentIPs) != 3 {
+		t.Fatalf("concurrent: %+v, %v", found, err)
+	}
+
+	// sessions of the window are remembered for revocation
+	members, err := s.Members(ctx, sessionsKey("7"))
+	if err != nil || len(members) != 2 {
+		t.Fatalf("sessions = %v, %v", members, err)
+	}
+}
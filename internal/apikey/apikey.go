@@ -0,0 +1,258 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/otel"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const Backend = "zerotrust.apikey"
+
+const (
+	DefaultPrefix         = "zt_"
+	defaultCacheKeyFormat = "zerotrust:api_key:{key}"
+	defaultMaxFailures    = 10
+	defaultFailureWindow  = 10 * time.Minute
+)
+
+var (
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrExpiredKey   = errors.New("api key expired")
+	ErrThrottledKey = errors.New("too many failed attempts for api key")
+)
+
+type Key struct {
+	ID        string    `yaml:"id" json:"id"`
+	Hash      string    `yaml:"hash" json:"hash"`
+	Owner     string    `yaml:"owner" json:"owner"`
+	Scopes    []string  `yaml:"scopes" json:"scopes"`
+	ExpiresAt time.Time `yaml:"expires_at" json:"expires_at"`
+	Hosts     []string  `yaml:"hosts" json:"hosts"`
+}
+
+type keyFile struct {
+	Keys []Key `yaml:"keys"`
+}
+
+var (
+	cfg      config.APIKeyConfig
+	fileKeys map[string]*Key
+	hashing  = make(chan struct{}, runtime.NumCPU())
+)
+
+func Init() {
+	cfg = config.Get().APIKey
+	if !cfg.Enabled {
+		return
+	}
+
+	// fill defaults
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.CacheKeyFormat == "" {
+		cfg.CacheKeyFormat = defaultCacheKeyFormat
+	}
+	if cfg.MaxFailures == 0 {
+		cfg.MaxFailures = defaultMaxFailures
+	}
+	if cfg.FailureWindow <= 0 {
+		cfg.FailureWindow = defaultFailureWindow
+	}
+	if cfg.File == "" {
+		return
+	}
+
+	// load keys from local file
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to read api key file")
+	}
+	var f keyFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		logrus.WithError(err).Fatal("failed to parse api key file")
+	}
+	fileKeys = make(map[string]*Key, len(f.Keys))
+	for i := range f.Keys {
+		if err := validateHash(f.Keys[i].Hash); err != nil {
+			logrus.WithError(err).WithField("key_id", f.Keys[i].ID).Fatal("invalid api key hash")
+		}
+		fileKeys[f.Keys[i].ID] = &f.Keys[i]
+	}
+}
+
+func Generate(prefix, algorithm string) (presented string, hash string, err error) {
+	// random id and secret, both url safe
+	id := make([]byte, 6)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	idText := prefix + hex.EncodeToString(id)
+	secretText := base64.RawURLEncoding.EncodeToString(secret)
+
+	// hash secret with the requested algorithm
+	switch algorithm {
+	case "sha256":
+		hash = HashSHA256(secretText)
+	case "argon2id":
+		if hash, err = HashArgon2(secretText); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", ErrUnsupportedHash
+	}
+	return idText + "." + secretText, hash, nil
+}
+
+func FromHeaders(authorization, header string) string {
+	// configured header wins over the authorization header
+	if header != "" {
+		return strings.TrimSpace(header)
+	}
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		token = strings.TrimSpace(token)
+		// jwt bearer tokens have three segments, api keys have two and start with the id prefix
+		if strings.HasPrefix(token, cfg.Prefix) && strings.Count(token, ".") == 1 {
+			return token
+		}
+	}
+	return ""
+}
+
+func Verify(ctx context.Context, presented string) (*Key, error) {
+	// start span
+	ctx, span := otel.Tracer().Start(ctx, "apikey.Verify")
+	defer span.End()
+
+	// keys look like <id>.<secret>
+	id, secret, ok := strings.Cut(presented, ".")
+	if !ok || id == "" || secret == "" || !strings.HasPrefix(id, cfg.Prefix) {
+		return nil, ErrInvalidKey
+	}
+
+	// look up key record
+	key, err := lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare secret against stored hash, unless the same key matched this hash shortly before
+	cacheKey := cacheKey(presented)
+	var cached string
+	if cfg.CacheTTL > 0 {
+		cached, _ = app.Default().Store.Get(ctx, cacheKey)
+	}
+	if cached == "" || cached != key.Hash {
+		matched, err := checkSecret(ctx, key, secret)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return nil, ErrInvalidKey
+		}
+		if cfg.CacheTTL > 0 {
+			if err := app.Default().Store.Set(ctx, cacheKey, key.Hash, cfg.CacheTTL); err != nil {
+				logrus.WithContext(ctx).WithError(err).Warn("[APIKey] failed to write key cache")
+			}
+		}
+	}
+
+	// check expiry
+	if !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt) {
+		return key, ErrExpiredKey
+	}
+
+	return key, nil
+}
+
+func lookup(ctx context.Context, id string) (*Key, error) {
+	// local file first
+	if key, ok := fileKeys[id]; ok {
+		return key, nil
+	}
+
+	// then redis hash holding json records
+	redisKey := cfg.RedisKey
+	if redisKey == "" {
+		return nil, ErrInvalidKey
+	}
+	data, err := app.Default().Store.HGet(ctx, redisKey, id)
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{}
+	if err := json.Unmarshal([]byte(data), key); err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+	if err := validateHash(key.Hash); err != nil {
+		return nil, fmt.Errorf("key %s: %w", id, err)
+	}
+	key.ID = id
+	return key, nil
+}
+
+func checkSecret(ctx context.Context, key *Key, secret string) (bool, error) {
+	// failures per key are limited whether or not rate_limit is enabled, redis errors let the attempt through
+	store := app.Default().Store
+	failuresKey := strings.Replace(cfg.CacheKeyFormat, "{key}", "failures:"+key.ID, 1)
+	if cfg.MaxFailures > 0 {
+		if value, err := store.Get(ctx, failuresKey); err == nil {
+			if failures, _ := strconv.Atoi(value); failures >= cfg.MaxFailures {
+				return false, ErrThrottledKey
+			}
+		}
+	}
+
+	// argon2id takes 64 MiB per run, at most one run per cpu at a time
+	select {
+	case hashing <- struct{}{}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	matched, err := verifyHash(key.Hash, secret)
+	<-hashing
+	if err != nil {
+		return false, fmt.Errorf("key %s: %w", key.ID, err)
+	}
+	if !matched && cfg.MaxFailures > 0 {
+		if _, _, err := store.Incr(ctx, failuresKey, cfg.FailureWindow); err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("[APIKey] failed to count key failure")
+		}
+	}
+	return matched, nil
+}
+
+func cacheKey(presented string) string {
+	// never keep raw keys in redis
+	sum := sha256.Sum256([]byte(presented))
+	return strings.Replace(cfg.CacheKeyFormat, "{key}", hex.EncodeToString(sum[:]), 1)
+}
+
+func (k *Key) AllowsHost(host string) bool {
+	return len(k.Hosts) == 0 || policy.MatchHost(k.Hosts, host)
+}
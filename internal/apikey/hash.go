@@ -0,0 +1,127 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported api key hash")
+
+const (
+	argon2MaxMemory     = 1024 * 1024
+	argon2MaxIterations = 16
+)
+
+const (
+	argon2Memory     = 64 * 1024
+	argon2Iterations = 3
+	argon2Threads    = 4
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+func HashArgon2(secret string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(secret), salt, argon2Iterations, argon2Memory, argon2Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Iterations, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+func HashSHA256(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+type argon2Hash struct {
+	memory     uint32
+	iterations uint32
+	threads    uint8
+	salt       []byte
+	key        []byte
+}
+
+func validateHash(encoded string) error {
+	if digest, ok := strings.CutPrefix(encoded, "sha256:"); ok {
+		if expected, err := hex.DecodeString(digest); err != nil || len(expected) != sha256.Size {
+			return ErrUnsupportedHash
+		}
+		return nil
+	}
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		_, err := parseArgon2(encoded)
+		return err
+	}
+	return ErrUnsupportedHash
+}
+
+func verifyHash(encoded, secret string) (bool, error) {
+	// sha256:<hex>
+	if digest, ok := strings.CutPrefix(encoded, "sha256:"); ok {
+		expected, err := hex.DecodeString(digest)
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+		}
+		sum := sha256.Sum256([]byte(secret))
+		return subtle.ConstantTimeCompare(sum[:], expected) == 1, nil
+	}
+
+	// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		return verifyArgon2(encoded, secret)
+	}
+
+	return false, ErrUnsupportedHash
+}
+
+func verifyArgon2(encoded, secret string) (bool, error) {
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	// derive and compare in constant time
+	actual := argon2.IDKey([]byte(secret), h.salt, h.iterations, h.memory, h.threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(actual, h.key) == 1, nil
+}
+
+func parseArgon2(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, ErrUnsupportedHash
+	}
+
+	// parse version and parameters, argon2 panics on zero threads and a stored hash must not stall the server
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnsupportedHash
+	}
+	h := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.threads); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if h.threads == 0 || h.iterations == 0 || h.iterations > argon2MaxIterations || h.memory < 8*uint32(h.threads) || h.memory > argon2MaxMemory {
+		return nil, fmt.Errorf("%w: argon2id parameters out of range", ErrUnsupportedHash)
+	}
+
+	// decode salt and hash
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) < 16 {
+		return nil, ErrUnsupportedHash
+	}
+	return h, nil
+}
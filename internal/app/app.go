@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/ovinc/zerotrust/internal/session"
+	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultName = "default"
+
+type Application struct {
+	Name    string
+	Hosts   []string
+	Auth    config.AuthConfig
+	Redis   config.RedisConfig
+	Store   *store.Store
+	Decoder *session.Decoder
+}
+
+var (
+	applications []*Application
+	fallback     *Application
+)
+
+func Init() {
+	ctx := context.Background()
+	cfg := config.Get()
+
+	// default profile comes from the top level redis and auth sections
+	var err error
+	fallback, err = newApplication(ctx, DefaultName, nil, cfg.Auth, cfg.Redis)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Fatal("failed to initialize default application")
+	}
+
+	// named profiles inherit anything they leave empty
+	applications = make([]*Application, 0, len(cfg.Applications))
+	for i := range cfg.Applications {
+		c := &cfg.Applications[i]
+		a, err := newApplication(ctx, c.Name, c.Hosts, mergeAuth(c, cfg.Auth), mergeRedis(&c.Redis, &cfg.Redis))
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).WithField("application", c.Name).Fatal("failed to initialize application")
+		}
+		applications = append(applications, a)
+	}
+}
+
+func newApplication(ctx context.Context, name string, hosts []string, auth config.AuthConfig, redis config.RedisConfig) (*Application, error) {
+	decoder, err := session.NewDecoder(redis.SessionDecoder, auth.SessionAttributes)
+	if err != nil {
+		return nil, err
+	}
+	s, err := store.New(ctx, &redis)
+	if err != nil {
+		return nil, err
+	}
+	return &Application{Name: name, Hosts: hosts, Auth: auth, Redis: redis, Store: s, Decoder: decoder}, nil
+}
+
+func mergeAuth(c *config.ApplicationConfig, auth config.AuthConfig) config.AuthConfig {
+	if c.SessionCookieName != "" {
+		auth.SessionCookieName = c.SessionCookieName
+	}
+	if c.SessionCookieDomain != "" {
+		auth.SessionCookieDomain = c.SessionCookieDomain
+	}
+	if c.LoginUrl != "" {
+		auth.LoginUrl = c.LoginUrl
+	}
+	if c.LoginRedirectParam != "" {
+		auth.LoginRedirectParam = c.LoginRedirectParam
+	}
+	if c.SessionAttributes != nil {
+		auth.SessionAttributes = c.SessionAttributes
+	}
+	if c.IdentityHeaders != nil {
+		auth.IdentityHeaders = c.IdentityHeaders
+	}
+	return auth
+}
+
+func mergeRedis(c *config.RedisConfig, redis *config.RedisConfig) config.RedisConfig {
+	merged := *c
+
+	// server is inherited as a whole, db is always taken from the profile
+	if merged.Host == "" {
+		merged.Host = redis.Host
+		merged.Port = redis.Port
+		merged.Password = redis.Password
+	}
+	if merged.SessionKeyFormat == "" {
+		merged.SessionKeyFormat = redis.SessionKeyFormat
+	}
+	if merged.SessionDecoder == "" {
+		merged.SessionDecoder = redis.SessionDecoder
+	}
+	return merged
+}
+
+func (a *Application) RevokeSession(ctx context.Context, sessionID string) error {
+	// django treats a missing session key as logged out
+	return a.Store.DeleteSession(ctx, sessionID)
+}
+
+func Resolve(host string) *Application {
+	// first profile whose host pattern matches wins
+	for _, a := range applications {
+		if policy.MatchHost(a.Hosts, host) {
+			return a
+		}
+	}
+	return fallback
+}
+
+func Lookup(name string) *Application {
+	for _, a := range All() {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+func Default() *Application {
+	return fallback
+}
+
+func All() []*Application {
+	return append([]*Application{fallback}, applications...)
+}
+
+func Ping(ctx context.Context) error {
+	var errs []error
+	for _, a := range All() {
+		if err := a.Store.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func Close() {
+	for _, a := range All() {
+		if a != nil {
+			a.Store.Close()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package assertion
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/jwt"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultHeader         = "X-ZeroTrust-Assertion"
+	defaultTTL            = time.Minute
+	defaultAccessTTL      = 5 * time.Minute
+	defaultReloadInterval = time.Minute
+)
+
+var reservedClaims = []string{
+	"iss", "sub", "aud", "iat", "nbf", "exp", "jti",
+	"backend", "authenticator", "auth_time", "request_id", "token_use",
+}
+
+type Subject struct {
+	UserID        string
+	Backend       string
+	Authenticator string
+	Attributes    map[string][]string
+	AuthTime      time.Time
+}
+
+var keys *keyring
+
+func Init() {
+	cfg := config.Get()
+	if !cfg.Assertion.Enabled && !cfg.TokenExchange.Enabled {
+		return
+	}
+
+	// attributes are added as claims and must not replace the ones set here
+	for _, name := range append(slices.Clone(cfg.Assertion.Attributes), cfg.TokenExchange.Attributes...) {
+		if slices.Contains(reservedClaims, name) {
+			logrus.WithField("attribute", name).Fatal("assertion attribute collides with a registered claim")
+		}
+	}
+
+	// signing keys must be available before serving
+	keys = &keyring{dir: cfg.Assertion.KeyDir}
+	if err := keys.load(); err != nil {
+		logrus.WithError(err).WithField("key_dir", cfg.Assertion.KeyDir).Fatal("failed to load assertion keys")
+	}
+
+	// pick up rotated keys in background
+	interval := cfg.Assertion.ReloadInterval
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := keys.load(); err != nil {
+				logrus.WithError(err).Warn("failed to reload assertion keys, keeping previous keys")
+			}
+		}
+	}()
+}
+
+func Enabled() bool {
+	return keys != nil && config.Get().Assertion.Enabled
+}
+
+func Header() string {
+	if header := config.Get().Assertion.Header; header != "" {
+		return header
+	}
+	return defaultHeader
+}
+
+func Mint(subject *Subject, host, requestID string) (string, error) {
+	cfg := config.Get().Assertion
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	// upstreams tell assertions from access tokens signed with the same key by token_use
+	claims := newClaims(subject, host, ttl, cfg.Attributes)
+	claims["token_use"] = "assertion"
+	if requestID != "" {
+		claims["request_id"] = requestID
+	}
+	return sign(claims)
+}
+
+func AccessToken(subject *Subject, audience string) (string, time.Duration, error) {
+	cfg := config.Get().TokenExchange
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultAccessTTL
+	}
+	claims := newClaims(subject, audience, ttl, cfg.Attributes)
+	claims["token_use"] = "access"
+	token, err := sign(claims)
+	return token, ttl, err
+}
+
+func newClaims(subject *Subject, audience string, ttl time.Duration, attributes []string) jwt.Claims {
+	// registered claims plus the identity
+	now := time.Now()
+	claims := jwt.Claims{
+		"iss":           config.Get().Assertion.Issuer,
+		"sub":           subject.UserID,
+		"aud":           audience,
+		"iat":           now.Unix(),
+		"nbf":           now.Unix(),
+		"exp":           now.Add(ttl).Unix(),
+		"backend":       subject.Backend,
+		"authenticator": subject.Authenticator,
+	}
+	if !subject.AuthTime.IsZero() {
+		claims["auth_time"] = subject.AuthTime.Unix()
+	}
+	for _, name := range attributes {
+		if values, ok := subject.Attributes[name]; ok {
+			claims[name] = values
+		}
+	}
+	return claims
+}
+
+func sign(claims jwt.Claims) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims["jti"] = hex.EncodeToString(jti)
+
+	key := keys.signer()
+	return jwt.Sign(claims, key.key, key.kid)
+}
+
+func JWKS() (*jwt.JWKS, error) {
+	if keys == nil {
+		return &jwt.JWKS{Keys: []jwt.JWK{}}, nil
+	}
+	return keys.jwks()
+}
@@ -0,0 +1,105 @@
+package assertion
+
+import (
+	"crypto"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/jwt"
+)
+
+var ErrNoKeys = errors.New("no signing keys in key directory")
+
+const JWKSMaxAge = 5 * time.Minute
+
+type signingKey struct {
+	kid       string
+	key       crypto.Signer
+	published time.Time
+}
+
+type keyring struct {
+	dir string
+
+	mu   sync.RWMutex
+	keys []signingKey
+}
+
+func (k *keyring) load() error {
+	// every <kid>.pem file in the directory is a key
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	k.mu.RLock()
+	previous := k.keys
+	k.mu.RUnlock()
+	keys := make([]signingKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := jwt.LoadPrivateKey(path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		// a key is published when the file appeared, or when a running instance first served it
+		published := info.ModTime()
+		if previous != nil {
+			published = time.Now()
+			for _, p := range previous {
+				if p.kid == kid {
+					published = p.published
+				}
+			}
+		}
+		keys = append(keys, signingKey{kid: kid, key: key, published: published})
+	}
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keyring) signer() signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	// the last kid by name signs once upstreams had time to fetch it, until then the one before
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if time.Since(k.keys[i].published) >= JWKSMaxAge {
+			return k.keys[i]
+		}
+	}
+	return k.keys[len(k.keys)-1]
+}
+
+func (k *keyring) jwks() (*jwt.JWKS, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	// publish retired keys too so assertions signed before a rotation still verify
+	set := &jwt.JWKS{Keys: make([]jwt.JWK, 0, len(k.keys))}
+	for _, sk := range k.keys {
+		pub := sk.key.Public()
+		jwk, err := jwt.NewJWK(pub, sk.kid, jwt.AlgorithmFor(pub))
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	return set, nil
+}
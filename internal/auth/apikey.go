@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ovinc/zerotrust/internal/apikey"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/session"
+)
+
+func init() {
+	register(&apiKeyAuthenticator{})
+}
+
+type apiKeyAuthenticator struct{}
+
+func (a *apiKeyAuthenticator) Name() string {
+	return "api_key"
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, req *Request) (*Identity, error) {
+	if !config.Get().APIKey.Enabled {
+		return nil, ErrNotApplicable
+	}
+	presented := apikey.FromHeaders(req.Authorization, req.APIKey)
+	if presented == "" {
+		return nil, ErrNotApplicable
+	}
+
+	// verify key against stored hashes
+	key, err := apikey.Verify(ctx, presented)
+	var authErr *Error
+	switch {
+	case errors.Is(err, apikey.ErrExpiredKey):
+		authErr = unauthorized("api_key_expired", nil)
+	case errors.Is(err, apikey.ErrInvalidKey):
+		authErr = unauthorized("invalid_api_key", nil)
+	case errors.Is(err, apikey.ErrThrottledKey):
+		authErr = unauthorized("api_key_throttled", nil)
+	case err != nil:
+		authErr = unauthorized("api_key_store_error", err)
+	case !key.AllowsHost(req.Host):
+		// key may be restricted to some hosts
+		authErr = forbidden("api_key_host_denied")
+	}
+	if authErr != nil {
+		if key != nil {
+			authErr.CredentialID = key.ID
+		}
+		return nil, authErr
+	}
+
+	// api keys act on behalf of their owner
+	return &Identity{
+		UserID:       key.Owner,
+		Backend:      apikey.Backend,
+		Attributes:   map[string][]string{session.AttributeScopes: key.Scopes},
+		CredentialID: key.ID,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNotApplicable = errors.New("authenticator not applicable")
+
+const ReasonMissingCredentials = "missing_session"
+
+var DefaultChain = []string{"session", "jwt", "mtls", "drf_token", "api_key"}
+
+type Request struct {
+	Application   *app.Application
+	Host          string
+	SessionID     string
+	Authorization string
+	APIKey        string
+	Headers       http.Header
+
+	// RemoteAddr is the peer that sent the request to us, PeerCertificates its verified tls chain
+	RemoteAddr       string
+	PeerCertificates []*x509.Certificate
+}
+
+type Identity struct {
+	UserID        string
+	Backend       string
+	Attributes    map[string][]string
+	Authenticator string
+	SessionID     string
+	CredentialID  string
+}
+
+type Error struct {
+	Authenticator string
+	Reason        string
+	Status        int
+	CredentialID  string
+	Err           error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Authenticator, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Authenticator, e.Reason)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func unauthorized(reason string, err error) *Error {
+	return &Error{Reason: reason, Status: http.StatusUnauthorized, Err: err}
+}
+
+func forbidden(reason string) *Error {
+	return &Error{Reason: reason, Status: http.StatusForbidden}
+}
+
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, req *Request) (*Identity, error)
+}
+
+var registry = map[string]Authenticator{}
+
+func register(a Authenticator) {
+	registry[a.Name()] = a
+}
+
+func Init() {
+	// load key material of authenticators that need it
+	initJWT(context.Background())
+	initMTLS(context.Background())
+
+	// make sure every configured chain only names known authenticators
+	cfg := config.Get()
+	if _, err := Chain(cfg.Auth.Authenticators); err != nil {
+		logrus.WithError(err).Fatal("invalid auth.authenticators")
+	}
+	for _, route := range cfg.Routes {
+		if _, err := Chain(route.Authenticators); err != nil {
+			logrus.WithError(err).WithField("route", route.Name).Fatal("invalid route authenticators")
+		}
+	}
+}
+
+func Chain(names []string) ([]Authenticator, error) {
+	if len(names) == 0 {
+		names = DefaultChain
+	}
+	chain := make([]Authenticator, 0, len(names))
+	for _, name := range names {
+		a, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown authenticator %q", name)
+		}
+		chain = append(chain, a)
+	}
+	return chain, nil
+}
+
+func ChainFor(route *config.RouteConfig) []Authenticator {
+	// route chain overrides the global one, both were validated at startup
+	names := config.Get().Auth.Authenticators
+	if route != nil && len(route.Authenticators) > 0 {
+		names = route.Authenticators
+	}
+	chain, _ := Chain(names)
+	return chain
+}
+
+func Authenticate(ctx context.Context, chain []Authenticator, req *Request) (*Identity, *Error) {
+	// first authenticator that succeeds wins, the first failure is kept for reporting
+	var failure *Error
+	for _, a := range chain {
+		identity, err := a.Authenticate(ctx, req)
+		if errors.Is(err, ErrNotApplicable) {
+			continue
+		}
+		if err != nil {
+			var authErr *Error
+			if !errors.As(err, &authErr) {
+				authErr = unauthorized(a.Name()+"_error", err)
+			}
+			authErr.Authenticator = a.Name()
+			if failure == nil {
+				failure = authErr
+			}
+			continue
+		}
+		identity.Authenticator = a.Name()
+		return identity, nil
+	}
+
+	if failure != nil {
+		return nil, failure
+	}
+	return nil, unauthorized(ReasonMissingCredentials, nil)
+}
+
+func MaskSecret(secret string) string {
+	// return masked placeholder for short secrets
+	if len(secret) <= 8 {
+		return "****"
+	}
+	// mask middle part for security
+	return secret[:4] + "****" + secret[len(secret)-4:]
+}
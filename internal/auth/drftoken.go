@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/drftoken"
+)
+
+func init() {
+	register(&drfTokenAuthenticator{})
+}
+
+type drfTokenAuthenticator struct{}
+
+func (a *drfTokenAuthenticator) Name() string {
+	return "drf_token"
+}
+
+func (a *drfTokenAuthenticator) Authenticate(ctx context.Context, req *Request) (*Identity, error) {
+	if !config.Get().DRFToken.Enabled {
+		return nil, ErrNotApplicable
+	}
+	token := drftoken.FromHeader(req.Authorization)
+	if token == "" {
+		return nil, ErrNotApplicable
+	}
+
+	// resolve token to its user
+	userID, err := drftoken.Lookup(ctx, token)
+	if err != nil {
+		authErr := unauthorized("token_store_error", err)
+		if errors.Is(err, drftoken.ErrInvalidToken) {
+			authErr = unauthorized("invalid_token", nil)
+		}
+		authErr.CredentialID = MaskSecret(token)
+		return nil, authErr
+	}
+
+	return &Identity{UserID: userID, Backend: drftoken.Backend, CredentialID: MaskSecret(token)}, nil
+}
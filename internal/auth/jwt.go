@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/jwt"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	register(&jwtAuthenticator{})
+}
+
+type jwtIssuer struct {
+	cfg  *config.JWTIssuerConfig
+	keys *jwt.KeySet
+}
+
+var jwtIssuers []*jwtIssuer
+
+func initJWT(ctx context.Context) {
+	cfg := config.Get().JWT
+	if !cfg.Enabled {
+		return
+	}
+
+	// load every issuer's key set and keep it fresh in background
+	jwtIssuers = make([]*jwtIssuer, 0, len(cfg.Issuers))
+	for i := range cfg.Issuers {
+		c := &cfg.Issuers[i]
+		if len(c.Audiences) == 0 {
+			logrus.WithContext(ctx).WithField("issuer", c.Name).Fatal("jwt issuer needs audiences")
+		}
+		keys := jwt.NewKeySet(c.JWKSFile, c.JWKSURL, c.RefreshInterval)
+		if err := keys.Start(ctx); err != nil {
+			logrus.WithContext(ctx).WithError(err).WithField("issuer", c.Name).Fatal("failed to load jwks")
+		}
+		jwtIssuers = append(jwtIssuers, &jwtIssuer{cfg: c, keys: keys})
+	}
+}
+
+type jwtAuthenticator struct{}
+
+func (a *jwtAuthenticator) Name() string {
+	return "jwt"
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, req *Request) (*Identity, error) {
+	if !config.Get().JWT.Enabled {
+		return nil, ErrNotApplicable
+	}
+	raw := bearerJWT(req)
+	if raw == "" {
+		return nil, ErrNotApplicable
+	}
+
+	// find issuer from the unverified claims
+	token, err := jwt.Parse(raw)
+	if err != nil {
+		return nil, unauthorized("invalid_jwt", err)
+	}
+	issuer := findIssuer(token.Claims.String("iss"))
+	if issuer == nil {
+		return nil, unauthorized("invalid_jwt", jwt.ErrInvalidIssuer)
+	}
+
+	// verify signature with the issuer key set
+	key, err := issuer.keys.Key(ctx, token.Header.Kid)
+	if errors.Is(err, jwt.ErrUnknownKey) {
+		return nil, unauthorized("invalid_jwt", err)
+	}
+	if err != nil {
+		return nil, unauthorized("jwks_error", err)
+	}
+	if err := token.Verify(key); err != nil {
+		return nil, unauthorized("invalid_jwt", err)
+	}
+
+	// identity assertions are only meant for upstreams, never as bearer tokens
+	if token.Claims.String("token_use") == "assertion" {
+		return nil, unauthorized("invalid_jwt", errors.New("assertion used as bearer token"))
+	}
+
+	// check validity window and audience
+	if err := token.ValidateTime(time.Now(), config.Get().JWT.ClockSkew, true); err != nil {
+		authErr := unauthorized("jwt_expired", err)
+		authErr.CredentialID = token.Claims.String("jti")
+		return nil, authErr
+	}
+	if !intersects(issuer.cfg.Audiences, token.Claims.Strings("aud")) {
+		return nil, unauthorized("invalid_jwt", jwt.ErrInvalidAudience)
+	}
+
+	// a token without a user would pass as an anonymous identity
+	identity := issuer.identity(token)
+	if identity.UserID == "" {
+		authErr := unauthorized("invalid_jwt", jwt.ErrMissingSubject)
+		authErr.CredentialID = identity.CredentialID
+		return nil, authErr
+	}
+	return identity, nil
+}
+
+func bearerJWT(req *Request) string {
+	// authorization header first, jwt has three segments
+	if token, ok := strings.CutPrefix(req.Authorization, "Bearer "); ok {
+		token = strings.TrimSpace(token)
+		if strings.Count(token, ".") == 2 {
+			return token
+		}
+	}
+
+	// then issuer specific headers such as Cf-Access-Jwt-Assertion
+	for _, issuer := range jwtIssuers {
+		for _, name := range issuer.cfg.Headers {
+			if token := strings.TrimSpace(req.Headers.Get(name)); token != "" {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+func findIssuer(iss string) *jwtIssuer {
+	for _, issuer := range jwtIssuers {
+		if issuer.cfg.Issuer == iss {
+			return issuer
+		}
+	}
+	return nil
+}
+
+func (i *jwtIssuer) identity(token *jwt.Token) *Identity {
+	userClaim := i.cfg.UserClaim
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+
+	// map configured claims into attributes
+	attributes := map[string][]string{}
+	for name, claim := range i.cfg.Claims {
+		if values := token.Claims.Strings(claim); len(values) > 0 {
+			attributes[name] = values
+		}
+	}
+
+	return &Identity{
+		UserID:       token.Claims.String(userClaim),
+		Backend:      "zerotrust.jwt." + i.cfg.Name,
+		Attributes:   attributes,
+		CredentialID: token.Claims.String("jti"),
+	}
+}
+
+func intersects(a, b []string) bool {
+	return slices.ContainsFunc(a, func(v string) bool { return slices.Contains(b, v) })
+}
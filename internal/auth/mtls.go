@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/clientcert"
+	"github.com/ovinc/zerotrust/internal/clientip"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	register(&mtlsAuthenticator{})
+}
+
+var DefaultClientCertHeaders = []string{"X-Forwarded-Client-Cert", "Ssl-Client-Cert"}
+
+var defaultCertIdentity = []string{"uri", "email", "dns", "cn"}
+
+type mtlsPool struct {
+	cfg   *config.MTLSPoolConfig
+	roots *x509.CertPool
+}
+
+var (
+	mtlsPools          []*mtlsPool
+	mtlsTrustedProxies []netip.Prefix
+)
+
+func initMTLS(ctx context.Context) {
+	cfg := config.Get().MTLS
+	if !cfg.Enabled {
+		return
+	}
+
+	// load ca pools
+	mtlsPools = make([]*mtlsPool, 0, len(cfg.Pools))
+	for i := range cfg.Pools {
+		c := &cfg.Pools[i]
+		roots, err := clientcert.LoadCertPool(c.CAFiles)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).WithField("pool", c.Name).Fatal("failed to load client ca pool")
+		}
+		mtlsPools = append(mtlsPools, &mtlsPool{cfg: c, roots: roots})
+	}
+
+	// only these peers may forward client certificates in headers
+	mtlsTrustedProxies = make([]netip.Prefix, 0, len(cfg.TrustedProxies))
+	for _, proxy := range cfg.TrustedProxies {
+		prefix, err := clientip.ParsePrefix(proxy)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Fatal("invalid mtls.trusted_proxies")
+		}
+		mtlsTrustedProxies = append(mtlsTrustedProxies, prefix)
+	}
+}
+
+type mtlsAuthenticator struct{}
+
+func (a *mtlsAuthenticator) Name() string {
+	return "mtls"
+}
+
+func (a *mtlsAuthenticator) Authenticate(ctx context.Context, req *Request) (*Identity, error) {
+	if !config.Get().MTLS.Enabled {
+		return nil, ErrNotApplicable
+	}
+
+	// certificate presented on our own tls listener, else forwarded by a proxy
+	chain := req.PeerCertificates
+	if len(chain) == 0 {
+		value := forwardedClientCert(req)
+		if value == "" {
+			return nil, ErrNotApplicable
+		}
+		if !trustedProxy(req.RemoteAddr) {
+			return nil, unauthorized("untrusted_client_cert_header", nil)
+		}
+		certs, err := clientcert.ParseHeader(value)
+		if err != nil {
+			return nil, unauthorized("invalid_client_cert", err)
+		}
+		chain = certs
+	}
+
+	// first pool that verifies the chain decides the identity
+	fingerprint := clientcert.Fingerprint(chain[0])
+	now := time.Now()
+	var verifyErr error
+	for _, pool := range mtlsPools {
+		if verifyErr = clientcert.Verify(chain, pool.roots, now); verifyErr == nil {
+			return pool.identity(chain[0], fingerprint)
+		}
+		if errors.Is(verifyErr, clientcert.ErrExpired) {
+			break
+		}
+	}
+
+	reason := "invalid_client_cert"
+	if errors.Is(verifyErr, clientcert.ErrExpired) {
+		reason = "client_cert_expired"
+	}
+	authErr := unauthorized(reason, verifyErr)
+	authErr.CredentialID = fingerprint
+	return nil, authErr
+}
+
+func forwardedClientCert(req *Request) string {
+	headers := config.Get().MTLS.Headers
+	if len(headers) == 0 {
+		headers = DefaultClientCertHeaders
+	}
+	for _, name := range headers {
+		// a header sent by the client comes before the line the proxy added
+		if values := req.Headers.Values(name); len(values) > 0 && values[len(values)-1] != "" {
+			return values[len(values)-1]
+		}
+	}
+	return ""
+}
+
+func trustedProxy(remoteAddr string) bool {
+	// mtls.trusted_proxies narrows auth.trusted_proxies
+	if len(mtlsTrustedProxies) == 0 {
+		return clientip.TrustedProxy(remoteAddr)
+	}
+	return clientip.Contains(mtlsTrustedProxies, remoteAddr)
+}
+
+func (p *mtlsPool) identity(cert *x509.Certificate, fingerprint string) (*Identity, error) {
+	kinds := p.cfg.Identity
+	if len(kinds) == 0 {
+		kinds = defaultCertIdentity
+	}
+	userID := clientcert.Identity(cert, kinds)
+	if userID == "" {
+		authErr := unauthorized("invalid_client_cert", errors.New("certificate has no usable name"))
+		authErr.CredentialID = fingerprint
+		return nil, authErr
+	}
+
+	// expose names for identity headers, organizational units act as groups
+	attributes := map[string][]string{}
+	if cert.Subject.CommonName != "" {
+		attributes["cn"] = []string{cert.Subject.CommonName}
+	}
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		attributes["groups"] = cert.Subject.OrganizationalUnit
+	}
+	if len(cert.DNSNames) > 0 {
+		attributes["dns"] = cert.DNSNames
+	}
+	if len(cert.EmailAddresses) > 0 {
+		attributes["email"] = cert.EmailAddresses
+	}
+	for _, uri := range cert.URIs {
+		attributes["uri"] = append(attributes["uri"], uri.String())
+	}
+
+	return &Identity{
+		UserID:       userID,
+		Backend:      "zerotrust.mtls." + p.cfg.Name,
+		Attributes:   attributes,
+		CredentialID: fingerprint,
+	}, nil
+}
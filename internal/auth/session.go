@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+)
+
+func init() {
+	register(&sessionAuthenticator{})
+}
+
+type sessionAuthenticator struct{}
+
+func (a *sessionAuthenticator) Name() string {
+	return "session"
+}
+
+func (a *sessionAuthenticator) Authenticate(ctx context.Context, req *Request) (*Identity, error) {
+	// check if session id is provided
+	if req.SessionID == "" {
+		return nil, ErrNotApplicable
+	}
+	application := req.Application
+
+	// get session data from redis
+	sessionData, err := application.Store.GetSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, unauthorized("session_store_error", err)
+	}
+
+	// parse django session to extract user info
+	userInfo, err := application.Decoder.Decode(ctx, []byte(sessionData))
+	if err != nil {
+		return nil, unauthorized("session_parse_error", err)
+	}
+
+	return &Identity{
+		UserID:       userInfo.UserID,
+		Backend:      userInfo.Backend,
+		Attributes:   userInfo.Attributes,
+		SessionID:    req.SessionID,
+		CredentialID: MaskSecret(req.SessionID),
+	}, nil
+}
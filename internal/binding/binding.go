@@ -0,0 +1,175 @@
+package binding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonMismatch = "session_binding_mismatch"
+
+const (
+	ActionAllow = "allow"
+	ActionFlag  = "flag"
+	ActionDeny  = "deny"
+)
+
+const (
+	defaultKeyFormat  = "zerotrust:session_binding:{session}"
+	defaultTTL        = 14 * 24 * time.Hour
+	defaultIPv4Prefix = 24
+	defaultIPv6Prefix = 48
+)
+
+type Fingerprint struct {
+	Network   string `json:"network,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	JA3       string `json:"ja3,omitempty"`
+}
+
+type Result struct {
+	Mismatches []string
+	Deny       bool
+}
+
+var cfg config.SessionBindingConfig
+
+func Init() {
+	cfg = config.Get().Binding
+	if !cfg.Enabled {
+		return
+	}
+
+	// fill defaults
+	if cfg.KeyFormat == "" {
+		cfg.KeyFormat = defaultKeyFormat
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.IPv4Prefix <= 0 {
+		cfg.IPv4Prefix = defaultIPv4Prefix
+	}
+	if cfg.IPv6Prefix <= 0 {
+		cfg.IPv6Prefix = defaultIPv6Prefix
+	}
+	if cfg.Action == "" {
+		cfg.Action = ActionDeny
+	}
+	if cfg.IPAction == "" {
+		cfg.IPAction = ActionFlag
+	}
+
+	// validate
+	if cfg.IPv4Prefix > 32 || cfg.IPv6Prefix > 128 {
+		logrus.Fatal("session_binding prefixes exceed the address length")
+	}
+	if cfg.Action != ActionFlag && cfg.Action != ActionDeny {
+		logrus.WithField("action", cfg.Action).Fatal("session_binding.action must be flag or deny")
+	}
+	if cfg.IPAction != ActionAllow && cfg.IPAction != ActionFlag && cfg.IPAction != ActionDeny {
+		logrus.WithField("ip_action", cfg.IPAction).Fatal("session_binding.ip_action must be allow, flag or deny")
+	}
+}
+
+func Enabled() bool {
+	return cfg.Enabled
+}
+
+func NewFingerprint(clientIP, userAgent string, headers map[string]string) *Fingerprint {
+	fp := &Fingerprint{UserAgent: userAgentFamily(userAgent)}
+	if addr, err := netip.ParseAddr(clientIP); err == nil {
+		bits := cfg.IPv6Prefix
+		if addr.Is4() {
+			bits = cfg.IPv4Prefix
+		}
+		prefix, _ := addr.Prefix(bits)
+		fp.Network = prefix.String()
+	}
+	if cfg.JA3Header != "" {
+		for name, value := range headers {
+			if strings.EqualFold(name, cfg.JA3Header) {
+				fp.JA3 = value
+				break
+			}
+		}
+	}
+	return fp
+}
+
+func Check(ctx context.Context, s *store.Store, sessionID string, fp *Fingerprint, bind bool) (*Result, error) {
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return nil, err
+	}
+	key := key(sessionID)
+
+	value, err := s.Get(ctx, key)
+	if errors.Is(err, redis.Nil) && !bind {
+		return &Result{}, nil
+	}
+	if errors.Is(err, redis.Nil) {
+		// the binding lives as long as the session key when it expires
+		ttl := cfg.TTL
+		if sessionTTL, err := s.SessionTTL(ctx, sessionID); err == nil && sessionTTL > 0 {
+			ttl = sessionTTL
+		}
+		created, setErr := s.SetNX(ctx, key, string(data), ttl)
+		if setErr != nil {
+			return nil, setErr
+		}
+		if created {
+			return &Result{}, nil
+		}
+
+		// a concurrent first request bound it already
+		value, err = s.Get(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// compare with the fingerprint of the first request
+	var bound Fingerprint
+	if err := json.Unmarshal([]byte(value), &bound); err != nil {
+		return nil, fmt.Errorf("invalid session binding: %w", err)
+	}
+	result := &Result{}
+	compare := func(part, boundValue, value, action string) {
+		if boundValue == value || action == ActionAllow {
+			return
+		}
+		result.Mismatches = append(result.Mismatches, part)
+		result.Deny = result.Deny || action == ActionDeny
+	}
+	compare("network", bound.Network, fp.Network, cfg.IPAction)
+	compare("user_agent", bound.UserAgent, fp.UserAgent, cfg.Action)
+	compare("ja3", bound.JA3, fp.JA3, cfg.Action)
+	return result, nil
+}
+
+func hash(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+func Extend(ctx context.Context, s *store.Store, sessionID string, ttl time.Duration) error {
+	// an expired binding would let the next client rebind a live session
+	_, err := s.ExtendKey(ctx, key(sessionID), ttl)
+	return err
+}
+
+func key(sessionID string) string {
+	return strings.ReplaceAll(cfg.KeyFormat, "{session}", hash(sessionID))
+}
@@ -0,0 +1,60 @@
+package binding
+
+import (
+	"testing"
+
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func TestUserAgentFamily(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{name: "chrome windows", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36", want: "Chrome/Windows"},
+		{name: "chrome update", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.6723.59 Safari/537.36", want: "Chrome/Windows"},
+		{name: "edge", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.2792.79", want: "Edge/Windows"},
+		{name: "opera", userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 OPR/114.0.0.0", want: "Opera/macOS"},
+		{name: "firefox linux", userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0", want: "Firefox/Linux"},
+		{name: "safari macos", userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15", want: "Safari/macOS"},
+		{name: "safari iphone", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1", want: "Safari/iOS"},
+		{name: "chrome ipad", userAgent: "Mozilla/5.0 (iPad; CPU OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/129.0.6668.69 Mobile/15E148 Safari/604.1", want: "Chrome/iOS"},
+		{name: "firefox iphone", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/131.0 Mobile/15E148 Safari/605.1.15", want: "Firefox/iOS"},
+		{name: "chrome android", userAgent: "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36", want: "Chrome/Android"},
+		{name: "samsung internet", userAgent: "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/26.0 Chrome/122.0.0.0 Mobile Safari/537.36", want: "Samsung Internet/Android"},
+		{name: "chromeos", userAgent: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36", want: "Chrome/ChromeOS"},
+		{name: "curl", userAgent: "curl/8.5.0", want: "curl"},
+		{name: "python requests", userAgent: "python-requests/2.32.3", want: "python-requests"},
+		{name: "empty", userAgent: "", want: ""},
+		{name: "blank", userAgent: "   ", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userAgentFamily(tt.userAgent); got != tt.want {
+				t.Fatalf("userAgentFamily() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFingerprint(t *testing.T) {
+	cfg = config.SessionBindingConfig{IPv4Prefix: 24, IPv6Prefix: 48, JA3Header: "X-JA3"}
+	t.Cleanup(func() { cfg = config.SessionBindingConfig{} })
+
+	tests := []struct {
+		clientIP string
+		headers  map[string]string
+		want     Fingerprint
+	}{
+		{clientIP: "203.0.113.77", want: Fingerprint{Network: "203.0.113.0/24", UserAgent: "curl"}},
+		{clientIP: "2001:db8:1234:5678::1", want: Fingerprint{Network: "2001:db8:1234::/48", UserAgent: "curl"}},
+		{clientIP: "", want: Fingerprint{UserAgent: "curl"}},
+		{clientIP: "203.0.113.77", headers: map[string]string{"x-ja3": "771,4865"}, want: Fingerprint{Network: "203.0.113.0/24", UserAgent: "curl", JA3: "771,4865"}},
+	}
+	for _, tt := range tests {
+		if got := NewFingerprint(tt.clientIP, "curl/8.5.0", tt.headers); *got != tt.want {
+			t.Errorf("NewFingerprint(%q) = %+v, want %+v", tt.clientIP, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package binding
+
+import "strings"
+
+var browsers = []struct{ token, family string }{
+	{"Edg/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+var systems = []struct{ token, family string }{
+	{"Windows", "Windows"},
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iOS"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+func userAgentFamily(userAgent string) string {
+	products := strings.Fields(userAgent)
+	if len(products) == 0 {
+		return ""
+	}
+
+	// non browser clients such as curl/8.5.0 are named by their first product
+	browser, _, _ := strings.Cut(products[0], "/")
+
+	// more specific tokens come first, most browsers also claim to be chrome or safari
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.family
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			return browser + "/" + s.family
+		}
+	}
+	return browser
+}
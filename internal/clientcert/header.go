@@ -0,0 +1,126 @@
+package clientcert
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var ErrNoCertificate = errors.New("no certificate in header")
+
+func ParseHeader(value string) ([]*x509.Certificate, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, ErrNoCertificate
+	}
+	// envoy X-Forwarded-Client-Cert
+	if isXFCC(value) {
+		return parseXFCC(value)
+	}
+
+	// url escaped or plain pem
+	if unescaped, err := url.PathUnescape(value); err == nil && strings.Contains(unescaped, "-----BEGIN") {
+		return parsePEM(unescaped)
+	}
+
+	// comma separated base64 der
+	var certs []*x509.Certificate
+	for _, part := range strings.Split(value, ",") {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(part), ""))
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func parsePEM(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+	return certs, nil
+}
+
+func isXFCC(value string) bool {
+	for _, key := range []string{"By=", "Hash=", "Cert=", "Chain=", "Subject=", "URI=", "DNS="} {
+		if strings.HasPrefix(value, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseXFCC(value string) ([]*x509.Certificate, error) {
+	// with APPEND_FORWARD the client controls the elements before the one the trusted proxy appended last
+	elements := splitUnquoted(value, ',')
+	fields := map[string]string{}
+	for _, pair := range splitUnquoted(elements[len(elements)-1], ';') {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(val, `"`) && strings.HasSuffix(val, `"`) && len(val) > 1 {
+			val = strings.ReplaceAll(val[1:len(val)-1], `\"`, `"`)
+		}
+		fields[strings.ToLower(key)] = val
+	}
+
+	// prefer the full chain when the proxy forwards it
+	raw := fields["chain"]
+	if raw == "" {
+		raw = fields["cert"]
+	}
+	if raw == "" {
+		return nil, ErrNoCertificate
+	}
+	data, err := url.PathUnescape(raw)
+	if err != nil {
+		return nil, err
+	}
+	return parsePEM(data)
+}
+
+func splitUnquoted(value string, sep rune) []string {
+	var parts []string
+	var quoted, escaped bool
+	start := 0
+	for i, c := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
@@ -0,0 +1,77 @@
+package clientcert
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ErrExpired = errors.New("certificate expired or not yet valid")
+
+func LoadCertPool(files []string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no certificates found", file)
+		}
+	}
+	return pool, nil
+}
+
+func Verify(chain []*x509.Certificate, roots *x509.CertPool, now time.Time) error {
+	leaf := chain[0]
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return ErrExpired
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
+
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func Identity(cert *x509.Certificate, kinds []string) string {
+	for _, kind := range kinds {
+		var value string
+		switch kind {
+		case "uri":
+			if len(cert.URIs) > 0 {
+				value = cert.URIs[0].String()
+			}
+		case "email":
+			if len(cert.EmailAddresses) > 0 {
+				value = cert.EmailAddresses[0]
+			}
+		case "dns":
+			if len(cert.DNSNames) > 0 {
+				value = cert.DNSNames[0]
+			}
+		case "cn":
+			value = cert.Subject.CommonName
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	headerXFF       = "X-Forwarded-For"
+	headerForwarded = "Forwarded"
+)
+
+var trustedProxies []netip.Prefix
+
+func Init() {
+	// compile trusted proxy ranges
+	cidrs := config.Get().Auth.TrustedProxies
+	trustedProxies = make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			logrus.WithError(err).Fatal("invalid auth.trusted_proxies")
+		}
+		trustedProxies = append(trustedProxies, prefix)
+	}
+
+	// without trusted proxies the direct peer is believed, as before the list existed
+	if len(trustedProxies) == 0 {
+		logrus.Warn("auth.trusted_proxies is empty, the last forwarded hop is taken as the client ip, set it to the proxy addresses")
+	}
+}
+
+func ParsePrefix(value string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		addr = addr.Unmap().WithZone("")
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	// mapped ipv4 prefixes count their bits within the ipv6 address
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() {
+		if bits < 96 {
+			return netip.Prefix{}, fmt.Errorf("netip.ParsePrefix(%q): mapped ipv4 prefix shorter than /96", value)
+		}
+		addr, bits = addr.Unmap(), bits-96
+	}
+	return netip.PrefixFrom(addr, bits).Masked(), nil
+}
+
+func FromRequest(r *http.Request) string {
+	// forwarding headers are only believed from trusted proxies, or from any peer when none are configured
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return ""
+	}
+	if !peerTrusted(peer) {
+		return peer.String()
+	}
+
+	header := config.Get().Auth.ClientIPHeader
+	if header == "" {
+		header = headerXFF
+	}
+	switch http.CanonicalHeaderKey(header) {
+	case headerXFF:
+		return walk(xffHops(r.Header.Values(headerXFF)), peer)
+	case headerForwarded:
+		return walk(forwardedHops(r.Header.Values(headerForwarded)), peer)
+	default:
+		// single value set by the edge, such as CF-Connecting-IP or True-Client-IP
+		if addr, ok := parseAddr(r.Header.Get(header)); ok {
+			return addr.String()
+		}
+		return peer.String()
+	}
+}
+
+func FromList(value string) string {
+	// the last hop stands for the peer, the rest is walked like the forwarding headers
+	hops := xffHops([]string{value})
+	peer, ok := parseAddr(hops[len(hops)-1])
+	if !ok {
+		return ""
+	}
+	if !contains(trustedProxies, peer) {
+		return peer.String()
+	}
+	return walk(hops[:len(hops)-1], peer)
+}
+
+func TrustedProxy(remoteAddr string) bool {
+	return Contains(trustedProxies, remoteAddr)
+}
+
+func Contains(prefixes []netip.Prefix, remoteAddr string) bool {
+	addr, ok := parseAddr(remoteAddr)
+	return ok && contains(prefixes, addr)
+}
+
+func peerTrusted(peer netip.Addr) bool {
+	return len(trustedProxies) == 0 || contains(trustedProxies, peer)
+}
+
+func walk(hops []string, peer netip.Addr) string {
+	// stop at the first untrusted hop, an unparsable hop cannot be trusted past
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !contains(trustedProxies, addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func xffHops(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+func forwardedHops(values []string) []string {
+	// rfc 7239: elements separated by commas, each with for= among ; separated pairs
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hop = strings.Trim(val, `"`)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return netip.Addr{}, false
+	}
+
+	// strip port, ipv6 may come bracketed with or without port
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
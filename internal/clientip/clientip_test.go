@@ -0,0 +1,149 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func setTrustedProxies(t *testing.T, header string, cidrs ...string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("auth:\n  client_ip_header: \""+header+"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.Init(path)
+
+	trustedProxies = nil
+	for _, cidr := range cidrs {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trustedProxies = append(trustedProxies, prefix)
+	}
+	t.Cleanup(func() { trustedProxies = nil })
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"10.1.2.3", "10.1.2.3/32"},
+		{"10.1.2.3/8", "10.0.0.0/8"},
+		{"::ffff:10.1.2.3", "10.1.2.3/32"},
+		{"::ffff:10.1.2.0/120", "10.1.2.0/24"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+		{"fe80::1%eth0", "fe80::1/128"},
+		{"::ffff:10.0.0.0/95", ""},
+		{"10.0.0.0/33", ""},
+		{"proxy.internal", ""},
+	}
+	for _, tt := range tests {
+		got, err := ParsePrefix(tt.value)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParsePrefix(%q) = %s, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != netip.MustParsePrefix(tt.want) {
+			t.Errorf("ParsePrefix(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	setTrustedProxies(t, "", "10.0.0.0/8", "2001:db8::/32")
+	peer := netip.MustParseAddr("10.0.0.1")
+
+	tests := []struct {
+		name string
+		hops []string
+		want string
+	}{
+		{name: "no hops", want: "10.0.0.1"},
+		{name: "client behind proxy", hops: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed first hop", hops: []string{"198.51.100.1", "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "proxy chain", hops: []string{"203.0.113.7", "10.0.0.2", "10.0.0.3"}, want: "203.0.113.7"},
+		{name: "all trusted", hops: []string{"10.0.0.9", "10.0.0.2"}, want: "10.0.0.9"},
+		{name: "unparsable hop", hops: []string{"203.0.113.7", "unknown", "10.0.0.2"}, want: "10.0.0.2"},
+		{name: "ipv6 with port", hops: []string{"[2001:db9::5]:443", "[2001:db8::1]:80"}, want: "2001:db9::5"},
+		{name: "mapped ipv4", hops: []string{"::ffff:203.0.113.7"}, want: "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := walk(tt.hops, peer); got != tt.want {
+				t.Fatalf("walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromList(t *testing.T) {
+	setTrustedProxies(t, "", "10.0.0.0/8")
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"203.0.113.7", "203.0.113.7"},
+		{"203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		{"198.51.100.1, 203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		{"198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{" 10.0.0.2 ", "10.0.0.2"},
+		{"", ""},
+		{"203.0.113.7, garbage", ""},
+	}
+	for _, tt := range tests {
+		if got := FromList(tt.value); got != tt.want {
+			t.Errorf("FromList(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		trusted []string
+		remote  string
+		xff     string
+		fwd     string
+		edge    string
+		want    string
+	}{
+		{name: "untrusted peer", trusted: []string{"10.0.0.0/8"}, remote: "198.51.100.1:1234", xff: "203.0.113.7", want: "198.51.100.1"},
+		{name: "trusted peer", trusted: []string{"10.0.0.0/8"}, remote: "10.0.0.1:1234", xff: "198.51.100.1, 203.0.113.7", want: "203.0.113.7"},
+		{name: "no trusted proxies", remote: "198.51.100.1:1234", xff: "192.0.2.1, 203.0.113.7", want: "203.0.113.7"},
+		{name: "forwarded", header: "Forwarded", trusted: []string{"10.0.0.0/8"}, remote: "10.0.0.1:1234",
+			fwd: `for=198.51.100.1, for="[2001:db8::7]:443";proto=https`, want: "2001:db8::7"},
+		{name: "edge header", header: "CF-Connecting-IP", trusted: []string{"10.0.0.0/8"}, remote: "10.0.0.1:1234", edge: "203.0.113.9", want: "203.0.113.9"},
+		{name: "edge header from untrusted peer", header: "CF-Connecting-IP", trusted: []string{"10.0.0.0/8"}, remote: "198.51.100.1:1234", edge: "203.0.113.9", want: "198.51.100.1"},
+		{name: "missing edge header", header: "CF-Connecting-IP", trusted: []string{"10.0.0.0/8"}, remote: "10.0.0.1:1234", want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTrustedProxies(t, tt.header, tt.trusted...)
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.fwd != "" {
+				r.Header.Set("Forwarded", tt.fwd)
+			}
+			if tt.edge != "" {
+				r.Header.Set("CF-Connecting-IP", tt.edge)
+			}
+			if got := FromRequest(r); got != tt.want {
+				t.Fatalf("FromRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package config
 
 import (
-	"flag"
 	"os"
 	"strings"
 
@@ -11,22 +10,28 @@ import (
 
 var cfg *Config
 
-func init() {
-	// parse command line flags
-	configPath := flag.String("config", "configs/config.yaml", "path to config file")
-	flag.Parse()
+func Init(path string) {
+	// load config file and keep it as the global config
+	c, err := Load(path)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load config file")
+	}
+	cfg = c
+}
 
+func Load(path string) (*Config, error) {
 	// read config file from disk
-	data, err := os.ReadFile(*configPath)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		logrus.WithError(err).Fatal("failed to read config file")
+		return nil, err
 	}
 
 	// parse yaml into config struct
-	cfg = &Config{}
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		logrus.WithError(err).Fatal("failed to parse config file")
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
 	}
+	return c, nil
 }
 
 func Get() *Config {
@@ -2,12 +2,20 @@ package config
 
 import "time"
 
+type TLSConfig struct {
+	CertFile      string   `yaml:"cert_file"`
+	KeyFile       string   `yaml:"key_file"`
+	ClientAuth    string   `yaml:"client_auth"`
+	ClientCAFiles []string `yaml:"client_ca_files"`
+}
+
 type ServerConfig struct {
 	Host         string        `yaml:"host"`
 	Port         int           `yaml:"port"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	TLS          TLSConfig     `yaml:"tls"`
 }
 
 type RedisConfig struct {
@@ -16,6 +24,7 @@ type RedisConfig struct {
 	Password         string `yaml:"password"`
 	DB               int    `yaml:"db"`
 	SessionKeyFormat string `yaml:"session_key_format"`
+	SessionDecoder   string `yaml:"session_decoder"`
 }
 
 type ResourceConfig struct {
@@ -31,17 +40,284 @@ type OTelConfig struct {
 }
 
 type AuthConfig struct {
-	ClientIPHeader     string   `yaml:"client_ip_header"`
-	SessionCookieName  string   `yaml:"session_cookie_name"`
-	LoginUrl           string   `yaml:"login_url"`
-	LoginRedirectParam string   `yaml:"login_redirect_param"`
-	TraceIDHeader      string   `yaml:"trace_id_header"`
-	VerifyMethods      []string `yaml:"verify_methods"`
+	ClientIPHeader      string            `yaml:"client_ip_header"`
+	TrustedProxies      []string          `yaml:"trusted_proxies"`
+	SessionCookieName   string            `yaml:"session_cookie_name"`
+	SessionCookieDomain string            `yaml:"session_cookie_domain"`
+	LoginUrl            string            `yaml:"login_url"`
+	LoginRedirectParam  string            `yaml:"login_redirect_param"`
+	TraceIDHeader       string            `yaml:"trace_id_header"`
+	VerifyMethods       []string          `yaml:"verify_methods"`
+	SessionAttributes   map[string]string `yaml:"session_attributes"`
+	IdentityHeaders     map[string]string `yaml:"identity_headers"`
+	Authenticators      []string          `yaml:"authenticators"`
+}
+
+type MatchConfig struct {
+	Hosts   []string `yaml:"hosts"`
+	Paths   []string `yaml:"paths"`
+	Methods []string `yaml:"methods"`
+}
+
+type RuleConfig struct {
+	Name     string      `yaml:"name"`
+	Match    MatchConfig `yaml:"match"`
+	Users    []string    `yaml:"users"`
+	Backends []string    `yaml:"backends"`
+	Groups   []string    `yaml:"groups"`
+	Scopes   []string    `yaml:"scopes"`
+	Action   string      `yaml:"action"`
+	Shadow   bool        `yaml:"shadow"`
+}
+
+type IPFilterConfig struct {
+	Allow          []string      `yaml:"allow"`
+	Deny           []string      `yaml:"deny"`
+	AllowFiles     []string      `yaml:"allow_files"`
+	DenyFiles      []string      `yaml:"deny_files"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+type GeoRuleConfig struct {
+	AllowCountries []string `yaml:"allow_countries"`
+	DenyCountries  []string `yaml:"deny_countries"`
+	AllowASNs      []uint   `yaml:"allow_asns"`
+	DenyASNs       []uint   `yaml:"deny_asns"`
+}
+
+type RouteConfig struct {
+	Name           string          `yaml:"name"`
+	Match          MatchConfig     `yaml:"match"`
+	Authenticators []string        `yaml:"authenticators"`
+	IPFilter       *IPFilterConfig `yaml:"ip_filter"`
+	Geo            *GeoRuleConfig  `yaml:"geo"`
+	Quota          *RateLimitRule  `yaml:"quota"`
+	MaxAuthAge     time.Duration   `yaml:"max_auth_age"`
+	StepUpExempt   []string        `yaml:"step_up_exempt"`
+}
+
+type TenantConfig struct {
+	Name        string      `yaml:"name"`
+	Match       MatchConfig `yaml:"match"`
+	HostPattern string      `yaml:"host_pattern"`
+	PathPattern string      `yaml:"path_pattern"`
+	Attribute   string      `yaml:"attribute"`
+	Query       string      `yaml:"query"`
+}
+
+type PolicyConfig struct {
+	DefaultAction string         `yaml:"default_action"`
+	Rules         []RuleConfig   `yaml:"rules"`
+	Tenants       []TenantConfig `yaml:"tenants"`
+}
+
+type ApplicationConfig struct {
+	Name                string            `yaml:"name"`
+	Hosts               []string          `yaml:"hosts"`
+	Redis               RedisConfig       `yaml:"redis"`
+	SessionCookieName   string            `yaml:"session_cookie_name"`
+	SessionCookieDomain string            `yaml:"session_cookie_domain"`
+	LoginUrl            string            `yaml:"login_url"`
+	LoginRedirectParam  string            `yaml:"login_redirect_param"`
+	SessionAttributes   map[string]string `yaml:"session_attributes"`
+	IdentityHeaders     map[string]string `yaml:"identity_headers"`
+}
+
+type GeoIPConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	CountryDatabase string        `yaml:"country_database"`
+	ASNDatabase     string        `yaml:"asn_database"`
+	ReloadInterval  time.Duration `yaml:"reload_interval"`
+	FlagASNs        []uint        `yaml:"flag_asns"`
+}
+
+type SessionBindingConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	KeyFormat  string        `yaml:"key_format"`
+	TTL        time.Duration `yaml:"ttl"`
+	IPv4Prefix int           `yaml:"ipv4_prefix"`
+	IPv6Prefix int           `yaml:"ipv6_prefix"`
+	JA3Header  string        `yaml:"ja3_header"`
+	Action     string        `yaml:"action"`
+	IPAction   string        `yaml:"ip_action"`
+}
+
+type AnomalyConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	KeyFormat     string        `yaml:"key_format"`
+	Window        time.Duration `yaml:"window"`
+	MaxSpeedKMH   float64       `yaml:"max_speed_kmh"`
+	MinDistanceKM float64       `yaml:"min_distance_km"`
+	MaxIPs        int           `yaml:"max_ips"`
+	Action        string        `yaml:"action"`
+}
+
+type RateLimitRule struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+}
+
+type RateLimitConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	KeyPrefix string        `yaml:"key_prefix"`
+	PerHost   bool          `yaml:"per_host"`
+	Total     RateLimitRule `yaml:"total"`
+	Failures  RateLimitRule `yaml:"failures"`
+}
+
+type SessionLimitConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	KeyFormat   string        `yaml:"key_format"`
+	TTL         time.Duration `yaml:"ttl"`
+	MaxSessions int           `yaml:"max_sessions"`
+	Action      string        `yaml:"action"`
+}
+
+type SessionTouchConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	KeyFormat   string        `yaml:"key_format"`
+	TTL         time.Duration `yaml:"ttl"`
+	Interval    time.Duration `yaml:"interval"`
+	MaxLifetime time.Duration `yaml:"max_lifetime"`
+}
+
+type StepUpConfig struct {
+	Attribute string `yaml:"attribute"`
+	Param     string `yaml:"param"`
+}
+
+type APIKeyConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Header         string        `yaml:"header"`
+	Prefix         string        `yaml:"prefix"`
+	File           string        `yaml:"file"`
+	RedisKey       string        `yaml:"redis_key"`
+	CacheKeyFormat string        `yaml:"cache_key_format"`
+	CacheTTL       time.Duration `yaml:"cache_ttl"`
+	MaxFailures    int           `yaml:"max_failures"`
+	FailureWindow  time.Duration `yaml:"failure_window"`
+}
+
+type DRFTokenConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Driver           string        `yaml:"driver"`
+	DSN              string        `yaml:"dsn"`
+	Query            string        `yaml:"query"`
+	CacheKeyFormat   string        `yaml:"cache_key_format"`
+	CacheTTL         time.Duration `yaml:"cache_ttl"`
+	NegativeCacheTTL time.Duration `yaml:"negative_cache_ttl"`
+}
+
+type UserSourceConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Driver         string        `yaml:"driver"`
+	DSN            string        `yaml:"dsn"`
+	Query          string        `yaml:"query"`
+	CacheKeyFormat string        `yaml:"cache_key_format"`
+	CacheTTL       time.Duration `yaml:"cache_ttl"`
+}
+
+type JWTIssuerConfig struct {
+	Name            string            `yaml:"name"`
+	Issuer          string            `yaml:"issuer"`
+	Audiences       []string          `yaml:"audiences"`
+	JWKSFile        string            `yaml:"jwks_file"`
+	JWKSURL         string            `yaml:"jwks_url"`
+	RefreshInterval time.Duration     `yaml:"refresh_interval"`
+	Headers         []string          `yaml:"headers"`
+	UserClaim       string            `yaml:"user_claim"`
+	Claims          map[string]string `yaml:"claims"`
+}
+
+type JWTConfig struct {
+	Enabled   bool              `yaml:"enabled"`
+	ClockSkew time.Duration     `yaml:"clock_skew"`
+	Issuers   []JWTIssuerConfig `yaml:"issuers"`
+}
+
+type MTLSPoolConfig struct {
+	Name     string   `yaml:"name"`
+	CAFiles  []string `yaml:"ca_files"`
+	Identity []string `yaml:"identity"`
+}
+
+type MTLSConfig struct {
+	Enabled        bool             `yaml:"enabled"`
+	Headers        []string         `yaml:"headers"`
+	TrustedProxies []string         `yaml:"trusted_proxies"`
+	Pools          []MTLSPoolConfig `yaml:"pools"`
+}
+
+type AssertionConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Header         string        `yaml:"header"`
+	Issuer         string        `yaml:"issuer"`
+	TTL            time.Duration `yaml:"ttl"`
+	KeyDir         string        `yaml:"key_dir"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	Attributes     []string      `yaml:"attributes"`
+}
+
+type TokenExchangeConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Audiences      []string      `yaml:"audiences"`
+	AllowedOrigins []string      `yaml:"allowed_origins"`
+	TTL            time.Duration `yaml:"ttl"`
+	Attributes     []string      `yaml:"attributes"`
+	CSRFCookieName string        `yaml:"csrf_cookie_name"`
+	CSRFHeader     string        `yaml:"csrf_header"`
+	RateLimit      int           `yaml:"rate_limit"`
+	RateWindow     time.Duration `yaml:"rate_window"`
+}
+
+type WhoamiConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type LogoutConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	RedirectParam    string   `yaml:"redirect_param"`
+	DefaultRedirect  string   `yaml:"default_redirect"`
+	AllowedRedirects []string `yaml:"allowed_redirects"`
+}
+
+type AdminConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	Scope           string        `yaml:"scope"`
+	AuditLog        string        `yaml:"audit_log"`
+	RecentDecisions int           `yaml:"recent_decisions"`
+	Failures        RateLimitRule `yaml:"failures"`
 }
 
 type Config struct {
-	Server ServerConfig `yaml:"server"`
-	Redis  RedisConfig  `yaml:"redis"`
-	OTel   OTelConfig   `yaml:"otel"`
-	Auth   AuthConfig   `yaml:"auth"`
+	Server        ServerConfig         `yaml:"server"`
+	Redis         RedisConfig          `yaml:"redis"`
+	OTel          OTelConfig           `yaml:"otel"`
+	Auth          AuthConfig           `yaml:"auth"`
+	Policy        PolicyConfig         `yaml:"policy"`
+	IPFilter      IPFilterConfig       `yaml:"ip_filter"`
+	GeoIP         GeoIPConfig          `yaml:"geoip"`
+	Binding       SessionBindingConfig `yaml:"session_binding"`
+	Anomaly       AnomalyConfig        `yaml:"anomaly"`
+	RateLimit     RateLimitConfig      `yaml:"rate_limit"`
+	SessionLimit  SessionLimitConfig   `yaml:"session_limit"`
+	SessionTouch  SessionTouchConfig   `yaml:"session_touch"`
+	StepUp        StepUpConfig         `yaml:"step_up"`
+	APIKey        APIKeyConfig         `yaml:"api_keys"`
+	DRFToken      DRFTokenConfig       `yaml:"drf_token"`
+	UserSource    UserSourceConfig     `yaml:"user_source"`
+	JWT           JWTConfig            `yaml:"jwt"`
+	MTLS          MTLSConfig           `yaml:"mtls"`
+	Assertion     AssertionConfig      `yaml:"assertion"`
+	TokenExchange TokenExchangeConfig  `yaml:"token_exchange"`
+	Whoami        WhoamiConfig         `yaml:"whoami"`
+	Logout        LogoutConfig         `yaml:"logout"`
+	Admin         AdminConfig          `yaml:"admin"`
+
+	Applications []ApplicationConfig `yaml:"applications"`
+	Routes       []RouteConfig       `yaml:"routes"`
 }
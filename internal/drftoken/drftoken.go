@@ -0,0 +1,121 @@
+package drftoken
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/otel"
+	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const Backend = "rest_framework.authtoken"
+
+const negativeValue = "-"
+
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrNotInitialized = errors.New("drf token source is not initialized")
+)
+
+var source *store.SQLStore
+
+func Init() {
+	ctx := context.Background()
+	cfg := config.Get().DRFToken
+	if !cfg.Enabled {
+		return
+	}
+
+	// connect to the django database
+	var err error
+	source, err = store.NewSQL(ctx, cfg.Driver, cfg.DSN)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Fatal("failed to initialize drf token source")
+	}
+}
+
+func Close() {
+	if source != nil {
+		source.Close()
+	}
+}
+
+func FromHeader(authorization string) string {
+	// drf uses the "Token" keyword, matched case insensitively like drf does
+	if scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " "); ok && strings.EqualFold(scheme, "Token") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func Lookup(ctx context.Context, token string) (string, error) {
+	// start span
+	ctx, span := otel.Tracer().Start(ctx, "drftoken.Lookup")
+	defer span.End()
+
+	cfg := config.Get().DRFToken
+	cache := app.Default().Store
+	cacheKey := cacheKey(&cfg, token)
+
+	// cache first, errors fall through to the database
+	if userID, err := cache.Get(ctx, cacheKey); err == nil {
+		if userID == negativeValue {
+			return "", ErrInvalidToken
+		}
+		return userID, nil
+	} else if !errors.Is(err, redis.Nil) {
+		logrus.WithContext(ctx).WithError(err).Warn("[DRFToken] failed to read token cache")
+	}
+
+	// query authtoken table
+	if source == nil {
+		return "", ErrNotInitialized
+	}
+	userID, err := source.QueryValue(ctx, query(&cfg), token)
+	if errors.Is(err, sql.ErrNoRows) {
+		if cfg.NegativeCacheTTL > 0 {
+			_ = cache.Set(ctx, cacheKey, negativeValue, cfg.NegativeCacheTTL)
+		}
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// fill cache
+	if cfg.CacheTTL > 0 {
+		if err := cache.Set(ctx, cacheKey, userID, cfg.CacheTTL); err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("[DRFToken] failed to write token cache")
+		}
+	}
+	return userID, nil
+}
+
+func query(cfg *config.DRFTokenConfig) string {
+	if cfg.Query != "" {
+		return cfg.Query
+	}
+	// default django tables with the driver specific placeholder, inactive users are rejected like in drf
+	if cfg.Driver == "mysql" {
+		return "SELECT t.user_id FROM authtoken_token t JOIN auth_user u ON u.id = t.user_id WHERE t.`key` = ? AND u.is_active"
+	}
+	return "SELECT t.user_id FROM authtoken_token t JOIN auth_user u ON u.id = t.user_id WHERE t.key = $1 AND u.is_active"
+}
+
+func cacheKey(cfg *config.DRFTokenConfig, token string) string {
+	// never keep raw tokens in redis
+	sum := sha256.Sum256([]byte(token))
+	format := cfg.CacheKeyFormat
+	if format == "" {
+		format = "zerotrust:drf_token:{token}"
+	}
+	return strings.Replace(format, "{token}", hex.EncodeToString(sum[:]), 1)
+}
@@ -0,0 +1,55 @@
+package geoip
+
+import (
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+type database struct {
+	file    string
+	reader  atomic.Pointer[maxminddb.Reader]
+	modTime time.Time
+}
+
+func openDatabase(file string) (*database, error) {
+	db := &database{file: file}
+	if _, err := db.load(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *database) load() (bool, error) {
+	info, err := os.Stat(db.file)
+	if err != nil {
+		return false, err
+	}
+	if db.reader.Load() != nil && info.ModTime().Equal(db.modTime) {
+		return false, nil
+	}
+	db.modTime = info.ModTime()
+
+	// read into memory instead of mmap so readers in flight survive a swap
+	data, err := os.ReadFile(db.file)
+	if err != nil {
+		return false, err
+	}
+	reader, err := maxminddb.OpenBytes(data)
+	if err != nil {
+		return false, err
+	}
+	db.reader.Store(reader)
+	return true, nil
+}
+
+func (db *database) lookup(addr netip.Addr, record any) error {
+	result := db.reader.Load().Lookup(addr)
+	if !result.Found() {
+		return result.Err()
+	}
+	return result.Decode(record)
+}
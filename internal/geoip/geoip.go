@@ -0,0 +1,176 @@
+package geoip
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonDenied = "geo_denied"
+
+const defaultReloadInterval = time.Minute
+
+type Location struct {
+	Country      string
+	ASN          uint
+	Organization string
+
+	// coordinates are only known with a city database
+	Latitude    float64
+	Longitude   float64
+	Coordinates bool
+}
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+var (
+	countryDB *database
+	asnDB     *database
+	flagASNs  []uint
+)
+
+func Init() {
+	cfg := config.Get()
+	if !cfg.GeoIP.Enabled {
+		for _, route := range cfg.Routes {
+			if route.Geo != nil {
+				logrus.WithField("route", route.Name).Fatal("route geo rules need geoip.enabled")
+			}
+		}
+		return
+	}
+
+	// open the configured databases, at least one is needed
+	if cfg.GeoIP.CountryDatabase == "" && cfg.GeoIP.ASNDatabase == "" {
+		logrus.Fatal("geoip needs country_database or asn_database")
+	}
+	countryDB = open(cfg.GeoIP.CountryDatabase)
+	asnDB = open(cfg.GeoIP.ASNDatabase)
+	flagASNs = cfg.GeoIP.FlagASNs
+
+	// reload databases in background
+	interval := cfg.GeoIP.ReloadInterval
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, db := range []*database{countryDB, asnDB} {
+				if db == nil {
+					continue
+				}
+				changed, err := db.load()
+				if err != nil {
+					logrus.WithError(err).WithField("file", db.file).Warn("failed to reload geoip database, keeping previous one")
+					continue
+				}
+				if changed {
+					logrus.WithField("file", db.file).Info("geoip database reloaded")
+				}
+			}
+		}
+	}()
+}
+
+func open(file string) *database {
+	if file == "" {
+		return nil
+	}
+	db, err := openDatabase(file)
+	if err != nil {
+		logrus.WithError(err).WithField("file", file).Fatal("failed to open geoip database")
+	}
+	return db
+}
+
+func Enabled() bool {
+	return countryDB != nil || asnDB != nil
+}
+
+func Lookup(ip string) *Location {
+	if !Enabled() {
+		return nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+
+	// unknown networks such as private ranges leave the fields empty
+	location := &Location{}
+	if countryDB != nil {
+		var record countryRecord
+		if err := countryDB.lookup(addr, &record); err != nil {
+			logrus.WithError(err).WithField("client_ip", ip).Warn("failed to look up country")
+		}
+		location.Country = record.Country.ISOCode
+		if location.Country == "" {
+			location.Country = record.RegisteredCountry.ISOCode
+		}
+		if record.Location.Latitude != nil && record.Location.Longitude != nil {
+			location.Latitude = *record.Location.Latitude
+			location.Longitude = *record.Location.Longitude
+			location.Coordinates = true
+		}
+	}
+	if asnDB != nil {
+		var record asnRecord
+		if err := asnDB.lookup(addr, &record); err != nil {
+			logrus.WithError(err).WithField("client_ip", ip).Warn("failed to look up asn")
+		}
+		location.ASN = record.Number
+		location.Organization = record.Organization
+	}
+	return location
+}
+
+func Allowed(route *config.RouteConfig, location *Location) bool {
+	if route == nil || route.Geo == nil {
+		return true
+	}
+	if location == nil {
+		location = &Location{}
+	}
+	rules := route.Geo
+
+	country := func(c string) bool { return strings.EqualFold(c, location.Country) }
+	if location.Country != "" && slices.ContainsFunc(rules.DenyCountries, country) {
+		return false
+	}
+	if location.ASN != 0 && slices.Contains(rules.DenyASNs, location.ASN) {
+		return false
+	}
+	if len(rules.AllowCountries) > 0 && !slices.ContainsFunc(rules.AllowCountries, country) {
+		return false
+	}
+	if len(rules.AllowASNs) > 0 && !slices.Contains(rules.AllowASNs, location.ASN) {
+		return false
+	}
+	return true
+}
+
+func Flagged(location *Location) bool {
+	return location != nil && location.ASN != 0 && slices.Contains(flagASNs, location.ASN)
+}
@@ -0,0 +1,142 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func openTestDatabases(t *testing.T) {
+	t.Helper()
+
+	// maxmind test databases from github.com/maxmind/MaxMind-DB/test-data
+	var err error
+	if countryDB, err = openDatabase("testdata/GeoLite2-City-Test.mmdb"); err != nil {
+		t.Fatal(err)
+	}
+	if asnDB, err = openDatabase("testdata/GeoLite2-ASN-Test.mmdb"); err != nil {
+		t.Fatal(err)
+	}
+	flagASNs = []uint{7018}
+	t.Cleanup(func() { countryDB, asnDB, flagASNs = nil, nil, nil })
+}
+
+func TestLookup(t *testing.T) {
+	openTestDatabases(t)
+
+	tests := []struct {
+		ip   string
+		want *Location
+	}{
+		{"81.2.69.142", &Location{Country: "GB", Latitude: 51.5142, Longitude: -0.0931, Coordinates: true}},
+		{"89.160.20.112", &Location{Country: "SE", ASN: 29518, Organization: "Bredband2 AB", Latitude: 58.4167, Longitude: 15.6167, Coordinates: true}},
+		{"1.128.0.1", &Location{ASN: 1221, Organization: "Telstra Pty Ltd"}},
+		{"2a02:cf40::1", &Location{Country: "NO", Latitude: 62, Longitude: 10, Coordinates: true}},
+		{"10.0.0.1", &Location{}},
+		{"not an ip", nil},
+	}
+	for _, tt := range tests {
+		got := Lookup(tt.ip)
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("Lookup(%q) = %+v, want %+v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	openTestDatabases(t)
+	gb := Lookup("81.2.69.142")
+	se := Lookup("89.160.20.112")
+	private := Lookup("10.0.0.1")
+
+	tests := []struct {
+		name     string
+		geo      *config.GeoRuleConfig
+		location *Location
+		want     bool
+	}{
+		{name: "no rules", location: gb, want: true},
+		{name: "allowed country", geo: &config.GeoRuleConfig{AllowCountries: []string{"gb"}}, location: gb, want: true},
+		{name: "other country", geo: &config.GeoRuleConfig{AllowCountries: []string{"GB"}}, location: se, want: false},
+		{name: "unknown country with allow list", geo: &config.GeoRuleConfig{AllowCountries: []string{"GB"}}, location: private, want: false},
+		{name: "denied country", geo: &config.GeoRuleConfig{DenyCountries: []string{"SE"}}, location: se, want: false},
+		{name: "unknown country with deny list", geo: &config.GeoRuleConfig{DenyCountries: []string{"SE"}}, location: private, want: true},
+		{name: "denied asn", geo: &config.GeoRuleConfig{DenyASNs: []uint{29518}}, location: se, want: false},
+		{name: "deny wins", geo: &config.GeoRuleConfig{AllowCountries: []string{"SE"}, DenyASNs: []uint{29518}}, location: se, want: false},
+		{name: "allowed asn", geo: &config.GeoRuleConfig{AllowASNs: []uint{29518}}, location: se, want: true},
+		{name: "missing asn", geo: &config.GeoRuleConfig{AllowASNs: []uint{29518}}, location: gb, want: false},
+		{name: "no location", geo: &config.GeoRuleConfig{AllowCountries: []string{"GB"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var route *config.RouteConfig
+			if tt.geo != nil {
+				route = &config.RouteConfig{Name: "test", Geo: tt.geo}
+			}
+			if got := Allowed(route, tt.location); got != tt.want {
+				t.Fatalf("Allowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagged(t *testing.T) {
+	openTestDatabases(t)
+	if !Flagged(Lookup("12.81.92.1")) {
+		t.Error("asn 7018 is not flagged")
+	}
+	if Flagged(Lookup("1.128.0.1")) || Flagged(Lookup("10.0.0.1")) || Flagged(nil) {
+		t.Error("unlisted asn is flagged")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asn.mmdb")
+	copyFile(t, "testdata/GeoLite2-ASN-Test.mmdb", path)
+	db, err := openDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := db.load(); changed || err != nil {
+		t.Fatalf("load() = %v, %v, want unchanged", changed, err)
+	}
+
+	// a replaced file is picked up, a broken one keeps the previous reader
+	copyFile(t, "testdata/GeoLite2-City-Test.mmdb", path)
+	touch(t, path, time.Minute)
+	if changed, err := db.load(); !changed || err != nil {
+		t.Fatalf("load() = %v, %v, want changed", changed, err)
+	}
+	if err := os.WriteFile(path, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, 2*time.Minute)
+	if _, err := db.load(); err == nil {
+		t.Fatal("load() of a broken file succeeded")
+	}
+	if got := db.reader.Load().Metadata.DatabaseType; got != "GeoLite2-City" {
+		t.Fatalf("database type = %q after failed reload", got)
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	data, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func touch(t *testing.T, path string, ahead time.Duration) {
+	t.Helper()
+	modTime := time.Now().Add(ahead)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -8,7 +8,7 @@ const htmlTemplateText = `<!DOCTYPE html>
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>身份验证失败</title>
+    <title>{{.title}}</title>
     <style>
         :root {
             --bg-color: #f8f9fa;
@@ -87,8 +87,8 @@ const htmlTemplateText = `<!DOCTYPE html>
                   d="M16.5 10.5V6.75a4.5 4.5 0 10-9 0v3.75m-.75 11.25h10.5a2.25 2.25 0 002.25-2.25v-6.75a2.25 2.25 0 00-2.25-2.25H6.75a2.25 2.25 0 00-2.25 2.25v6.75a2.25 2.25 0 002.25 2.25z"/>
         </svg>
     </div>
-    <h1>身份验证失败</h1>
-    <p>无法验证您的身份信息，请登录后重试</p>
+    <h1>{{.title}}</h1>
+    <p>{{.message}}</p>
 	<p style="display: {{.traceIdDisplayStyle}}">{{.traceID}}</p>
     <button class="redirect-btn" onclick="window.location.href='{{.url}}'">前往登录</button>
 </div>
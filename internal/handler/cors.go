@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+func requestOrigin(r *http.Request) string {
+	// browsers send origin on cross origin and post requests, fall back to referer like django
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return strings.TrimSuffix(origin, "/")
+	}
+	referer, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || referer.Host == "" {
+		return ""
+	}
+	return referer.Scheme + "://" + referer.Host
+}
+
+func originAllowed(r *http.Request, origin string, allowed []string) bool {
+	if origin == "" {
+		return false
+	}
+
+	// without an allow list only the page's own origin may call
+	if len(allowed) == 0 {
+		scheme := r.Header.Get("X-Forwarded-Proto")
+		if scheme == "" {
+			scheme = "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+		}
+		return origin == scheme+"://"+requestHost(r)
+	}
+	return slices.Contains(allowed, origin)
+}
+
+func setCORSHeaders(w http.ResponseWriter, origin string, methods, headers []string) {
+	// credentialed cors needs the exact origin, never a wildcard
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	w.Header().Set("Access-Control-Max-Age", "600")
+	w.Header().Add("Vary", "Origin")
+}
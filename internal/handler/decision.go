@@ -0,0 +1,497 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/admin"
+	"github.com/ovinc/zerotrust/internal/anomaly"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/assertion"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/binding"
+	"github.com/ovinc/zerotrust/internal/geoip"
+	"github.com/ovinc/zerotrust/internal/ipfilter"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/ovinc/zerotrust/internal/ratelimit"
+	"github.com/ovinc/zerotrust/internal/session"
+	"github.com/ovinc/zerotrust/internal/sessionlimit"
+	"github.com/ovinc/zerotrust/internal/sessiontouch"
+	"github.com/ovinc/zerotrust/internal/stepup"
+	"github.com/ovinc/zerotrust/internal/usersource"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ResultSkipped      = "request skipped"
+	ResultUnauthorized = "request unauthorized"
+	ResultForbidden    = "request forbidden"
+	ResultRateLimited  = "request rate limited"
+	ResultAuthorized   = "request authorized"
+	ResultError        = "request error"
+)
+
+type Decision struct {
+	Status        int
+	Result        string
+	Reason        string
+	Application   string
+	Route         string
+	Authenticator string
+	Identity      *auth.Identity
+	UserID        string
+	SessionID     string
+	CredentialID  string
+	Headers       http.Header
+	RetryAfter    time.Duration
+	Geo           *geoip.Location
+	Binding       *binding.Result
+	Anomaly       *anomaly.Anomaly
+	Tenant        *policy.TenantResult
+	Policy        *policy.Decision
+	StepUp        *stepup.Result
+	Quota         *ratelimit.Quota
+	Extended      bool
+	Err           error
+}
+
+func Authorize(ctx context.Context, req *VerifyRequest) *Decision {
+	decision := authorize(ctx, req)
+
+	// failed attempts count towards the failure limit, requests without credentials or a recent login do not
+	failed := decision.Status == http.StatusUnauthorized || decision.Status == http.StatusForbidden
+	if failed && decision.Reason != auth.ReasonMissingCredentials && decision.Reason != stepup.ReasonReauthRequired {
+		ratelimit.Fail(ctx, req.ClientIP, req.Host)
+	}
+	return decision
+}
+
+func authorize(ctx context.Context, req *VerifyRequest) *Decision {
+	// pick application profile by host and route by request
+	application := app.Resolve(req.Host)
+	route := policy.ResolveRoute(req.Host, req.Method, req.Path)
+	decision := &Decision{Application: application.Name}
+	if route != nil {
+		decision.Route = route.Name
+	}
+
+	// reject blocked networks before touching any store
+	if !ipfilter.Allowed(route, req.ClientIP) {
+		return decision.forbidden(ipfilter.ReasonDenied)
+	}
+
+	// locate the client and apply route geo rules
+	decision.Geo = geoip.Lookup(req.ClientIP)
+	if decision.Geo != nil {
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("geo.country", decision.Geo.Country),
+			attribute.Int64("geo.asn", int64(decision.Geo.ASN)),
+			attribute.String("geo.as_org", decision.Geo.Organization),
+			attribute.Bool("geo.flagged", geoip.Flagged(decision.Geo)),
+		)
+	}
+	if !geoip.Allowed(route, decision.Geo) {
+		return decision.forbidden(geoip.ReasonDenied)
+	}
+
+	// slow down clients that send too many or too many failing requests
+	if retryAfter, ok := ratelimit.Allow(ctx, req.ClientIP, req.Host); !ok {
+		return decision.limited(ratelimit.ReasonLimited, retryAfter)
+	}
+
+	// check methods
+	skipVerify := true
+	reqMethod := strings.ToLower(req.Method)
+	for _, m := range application.Auth.VerifyMethods {
+		if strings.ToLower(m) == reqMethod {
+			skipVerify = false
+			break
+		}
+	}
+	if skipVerify && !req.sessionOnly {
+		return decision.skip("method_not_verified")
+	}
+
+	// run the authenticator chain of the route, endpoints acting on the cookie only accept the session
+	chain := auth.ChainFor(route)
+	if req.sessionOnly {
+		chain, _ = auth.Chain([]string{"session"})
+	}
+	identity, authErr := auth.Authenticate(ctx, chain, &auth.Request{
+		Application:   application,
+		Host:          req.Host,
+		SessionID:     req.SessionID,
+		Authorization: req.Authorization,
+		APIKey:        req.APIKey,
+		Headers:       req.header(),
+
+		RemoteAddr:       req.RemoteAddr,
+		PeerCertificates: req.PeerCertificates,
+	})
+	if authErr != nil {
+		decision.Authenticator = authErr.Authenticator
+		decision.CredentialID = authErr.CredentialID
+		if authErr.Authenticator == "session" {
+			decision.SessionID = req.SessionID
+		}
+		if authErr.Status == http.StatusForbidden {
+			return decision.forbidden(authErr.Reason)
+		}
+		return decision.unauthorized(authErr.Reason, authErr.Err)
+	}
+	decision.Identity = identity
+	decision.Authenticator = identity.Authenticator
+	decision.CredentialID = identity.CredentialID
+	decision.SessionID = identity.SessionID
+	decision.UserID = identity.UserID
+
+	// sessions stay bound to the client that first used them, store errors fail open
+	if binding.Enabled() && identity.SessionID != "" {
+		fingerprint := binding.NewFingerprint(req.ClientIP, req.UserAgent, req.Headers)
+		result, err := binding.Check(ctx, application.Store, identity.SessionID, fingerprint, !req.readOnly)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("failed to check session binding")
+		}
+		decision.Binding = result
+		if result != nil && result.Deny {
+			return decision.unauthorized(binding.ReasonMismatch, nil)
+		}
+	}
+
+	// far apart or too many ips for one user are audited, reauth logs the user out everywhere
+	if anomaly.Enabled() && !req.readOnly && identity.SessionID != "" && identity.UserID != "" {
+		found, err := anomaly.Check(ctx, application.Store, identity.UserID, identity.SessionID, req.ClientIP, decision.Geo)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("failed to check anomalies")
+		}
+		if found != nil {
+			decision.Anomaly = found
+			if !anomaly.Reauth() {
+				auditAnomaly(ctx, req, identity, found, nil)
+			} else {
+				// django would accept the sessions again, so all of them are revoked
+				revoked, err := anomaly.Revoke(ctx, application, identity.UserID, identity.SessionID)
+				if err != nil {
+					logrus.WithContext(ctx).WithError(err).Warn("failed to revoke anomalous sessions")
+				}
+				auditAnomaly(ctx, req, identity, found, revoked)
+				return decision.unauthorized(anomaly.ReasonReauth, nil)
+			}
+		}
+	}
+
+	// a user holds a limited number of sessions, new ones are denied or replace the oldest
+	if sessionlimit.Enabled() && !req.readOnly && identity.SessionID != "" && identity.UserID != "" {
+		result, err := sessionlimit.Track(ctx, application, identity.UserID, identity.SessionID)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("failed to track user sessions")
+		}
+		if result != nil {
+			auditSessionLimit(ctx, req, identity, result)
+			if result.Denied {
+				return decision.forbidden(sessionlimit.ReasonExceeded)
+			}
+		}
+	}
+
+	// tenant hosts are only reachable by members of that tenant
+	input := &policy.Input{
+		Host:       req.Host,
+		Method:     req.Method,
+		Path:       req.Path,
+		UserID:     identity.UserID,
+		Backend:    identity.Backend,
+		Groups:     identity.Attributes[session.AttributeGroups],
+		Scopes:     identity.Attributes[session.AttributeScopes],
+		Attributes: identity.Attributes,
+	}
+	var profile policy.ProfileLookup
+	if usersource.Enabled() {
+		profile = usersource.Values
+	}
+	tenant, err := policy.CheckTenant(ctx, input, profile)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("failed to look up tenant in user profile")
+	}
+	decision.Tenant = tenant
+	if decision.Tenant != nil && !decision.Tenant.Allowed {
+		return decision.forbidden(policy.ReasonTenantMismatch)
+	}
+
+	// evaluate access rules against the authenticated user
+	decision.Policy = policy.Evaluate(ctx, input)
+	if !decision.Policy.Allowed {
+		return decision.forbidden(decision.Policy.Reason)
+	}
+
+	// sensitive routes need a recent login, older sessions are sent back to log in again
+	stepUp, err := stepup.Check(ctx, route, identity)
+	if stepUp != nil {
+		decision.StepUp = stepUp
+		if stepUp.Stale {
+			return decision.unauthorized(stepup.ReasonReauthRequired, err)
+		}
+	}
+
+	// count the request against the user's route quota, the headers go out either way
+	decision.Headers = identityHeaders(application, identity)
+	var quota *ratelimit.Quota
+	if !req.readOnly {
+		quota, err = ratelimit.CheckQuota(ctx, application.Store, route, identity.UserID)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("failed to count quota")
+		}
+	}
+	if quota != nil {
+		decision.Quota = quota
+		quota.SetHeaders(decision.Headers)
+		if quota.Exceeded {
+			return decision.limited(ratelimit.ReasonQuotaExceeded, quota.Reset)
+		}
+	}
+
+	// pass identity to upstream, signed when assertions are enabled
+	if assertion.Enabled() && !req.sessionOnly {
+		token, err := assertion.Mint(&assertion.Subject{
+			UserID:        identity.UserID,
+			Backend:       identity.Backend,
+			Authenticator: identity.Authenticator,
+			Attributes:    identity.Attributes,
+		}, req.Host, req.RequestID)
+		if err != nil {
+			return decision.fail("assertion_error", err)
+		}
+		decision.Headers.Set(assertion.Header(), token)
+	}
+
+	// keep sessions in use alive although their requests never reach django
+	if sessiontouch.Enabled() && !req.readOnly && identity.SessionID != "" {
+		extended, err := sessiontouch.Touch(ctx, application.Store, identity.SessionID)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("failed to extend session")
+		}
+		decision.Extended = extended
+	}
+	decision.Status = http.StatusOK
+	decision.Result = ResultAuthorized
+	return decision
+}
+
+func auditAnomaly(ctx context.Context, req *VerifyRequest, identity *auth.Identity, found *anomaly.Anomaly, revoked []string) {
+	fields := logrus.Fields{
+		"user_id":      identity.UserID,
+		"session_id":   auth.MaskSecret(identity.SessionID),
+		"client_ip":    req.ClientIP,
+		"host":         req.Host,
+		"request_id":   req.RequestID,
+		"distinct_ips": found.IPs,
+		"recent_ips":   found.RecentIPs,
+	}
+	if revoked != nil {
+		masked := make([]string, len(revoked))
+		for i, sessionID := range revoked {
+			masked[i] = auth.MaskSecret(sessionID)
+		}
+		fields["revoked"] = masked
+	}
+	if found.Kind == anomaly.KindImpossibleTravel {
+		fields["previous_ip"] = found.PreviousIP
+		fields["distance_km"] = found.DistanceKM
+		fields["speed_kmh"] = found.SpeedKMH
+	}
+	admin.Event(ctx, "anomaly."+found.Kind, fields)
+}
+
+func auditSessionLimit(ctx context.Context, req *VerifyRequest, identity *auth.Identity, result *sessionlimit.Result) {
+	fields := logrus.Fields{
+		"user_id":    identity.UserID,
+		"session_id": auth.MaskSecret(identity.SessionID),
+		"client_ip":  req.ClientIP,
+		"host":       req.Host,
+		"request_id": req.RequestID,
+	}
+	if result.Denied {
+		admin.Event(ctx, "session_limit.denied", fields)
+		return
+	}
+	revoked := make([]string, len(result.Revoked))
+	for i, sessionID := range result.Revoked {
+		revoked[i] = auth.MaskSecret(sessionID)
+	}
+	fields["revoked"] = revoked
+	admin.Event(ctx, "session_limit.revoked", fields)
+}
+
+func (req *VerifyRequest) header() http.Header {
+	// canonicalize header names sent in the verify body
+	header := make(http.Header, len(req.Headers))
+	for name, value := range req.Headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+func identityHeaders(application *app.Application, identity *auth.Identity) http.Header {
+	headers := http.Header{}
+	for field, name := range application.Auth.IdentityHeaders {
+		var value string
+		switch field {
+		case "user_id":
+			value = identity.UserID
+		case "backend":
+			value = identity.Backend
+		default:
+			value = strings.Join(identity.Attributes[field], ",")
+		}
+		if value != "" {
+			headers.Set(name, value)
+		}
+	}
+	return headers
+}
+
+func (d *Decision) skip(reason string) *Decision {
+	d.Status = http.StatusOK
+	d.Result = ResultSkipped
+	d.Reason = reason
+	return d
+}
+
+func (d *Decision) unauthorized(reason string, err error) *Decision {
+	d.Status = http.StatusUnauthorized
+	d.Result = ResultUnauthorized
+	d.Reason = reason
+	d.Err = err
+	return d
+}
+
+func (d *Decision) forbidden(reason string) *Decision {
+	d.Status = http.StatusForbidden
+	d.Result = ResultForbidden
+	d.Reason = reason
+	return d
+}
+
+func (d *Decision) limited(reason string, retryAfter time.Duration) *Decision {
+	d.Status = http.StatusTooManyRequests
+	d.Result = ResultRateLimited
+	d.Reason = reason
+	d.RetryAfter = retryAfter
+	return d
+}
+
+func (d *Decision) fail(reason string, err error) *Decision {
+	d.Status = http.StatusInternalServerError
+	d.Result = ResultError
+	d.Reason = reason
+	d.Err = err
+	return d
+}
+
+func logDecision(ctx context.Context, req *VerifyRequest, d *Decision) {
+	fields := logrus.Fields{
+		"client_ip":   req.ClientIP,
+		"method":      req.Method,
+		"protocol":    req.Protocol,
+		"host":        req.Host,
+		"path":        req.Path,
+		"request_id":  req.RequestID,
+		"user_agent":  req.UserAgent,
+		"referer":     req.Referer,
+		"application": d.Application,
+		"user_id":     d.UserID,
+		"session_id":  "",
+		"reason":      d.Reason,
+	}
+	if d.SessionID != "" {
+		fields["session_id"] = auth.MaskSecret(d.SessionID)
+	}
+	if d.Route != "" {
+		fields["route"] = d.Route
+	}
+	if d.Authenticator != "" {
+		fields["authenticator"] = d.Authenticator
+	}
+	if d.CredentialID != "" && d.SessionID == "" {
+		fields["credential_id"] = d.CredentialID
+	}
+	if d.Geo != nil {
+		fields["geo_country"] = d.Geo.Country
+		fields["geo_asn"] = d.Geo.ASN
+		fields["geo_as_org"] = d.Geo.Organization
+		fields["geo_flagged"] = geoip.Flagged(d.Geo)
+	}
+	if d.Binding != nil && len(d.Binding.Mismatches) > 0 {
+		fields["session_binding_mismatch"] = d.Binding.Mismatches
+	}
+	if d.Anomaly != nil {
+		fields["anomaly"] = d.Anomaly.Kind
+	}
+	if d.StepUp != nil && !d.StepUp.AuthTime.IsZero() {
+		fields["auth_time_source"] = d.StepUp.Source
+		fields["auth_age"] = int(time.Since(d.StepUp.AuthTime).Seconds())
+	}
+	if d.Quota != nil {
+		fields["quota_remaining"] = d.Quota.Remaining
+	}
+	if d.Extended {
+		fields["session_extended"] = true
+	}
+	if d.Tenant != nil {
+		fields["tenant"] = d.Tenant.Tenant
+	}
+	if d.Policy != nil && d.Policy.Rule != "" {
+		fields["rule"] = d.Policy.Rule
+	}
+	if d.Policy != nil && d.Policy.Shadow != nil {
+		fields["shadow_reason"] = d.Policy.Shadow.Reason
+		fields["shadow_rule"] = d.Policy.Shadow.Rule
+		fields["shadow_disagrees"] = d.Policy.Disagrees()
+	}
+
+	entry := logrus.WithContext(ctx).WithFields(fields)
+	if d.Err != nil {
+		entry.WithError(d.Err).Warn(d.Result)
+		return
+	}
+	entry.Info(d.Result)
+}
+
+func recordDecision(req *VerifyRequest, d *Decision) {
+	// keep for the admin api, session ids stay masked
+	entry := admin.DecisionEntry{
+		Time:          time.Now(),
+		ClientIP:      req.ClientIP,
+		Method:        req.Method,
+		Host:          req.Host,
+		Path:          req.Path,
+		RequestID:     req.RequestID,
+		Status:        d.Status,
+		Result:        d.Result,
+		Reason:        d.Reason,
+		Application:   d.Application,
+		Route:         d.Route,
+		Authenticator: d.Authenticator,
+		UserID:        d.UserID,
+		CredentialID:  d.CredentialID,
+	}
+	if d.SessionID != "" {
+		entry.SessionID = auth.MaskSecret(d.SessionID)
+		entry.CredentialID = ""
+	}
+	if d.Geo != nil {
+		entry.Country = d.Geo.Country
+		entry.ASN = d.Geo.ASN
+	}
+	if d.Tenant != nil {
+		entry.Tenant = d.Tenant.Tenant
+	}
+	if d.Policy != nil {
+		entry.Rule = d.Policy.Rule
+	}
+	admin.Record(entry)
+}
@@ -3,12 +3,12 @@ package handler
 import (
 	"net/http"
 
-	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/ovinc/zerotrust/internal/app"
 )
 
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	// store ping
-	if err := store.Ping(r.Context()); err != nil {
+	// store ping for every application
+	if err := app.Ping(r.Context()); err != nil {
 		http.Error(w, "store unreachable", http.StatusServiceUnavailable)
 		return
 	}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ovinc/zerotrust/internal/assertion"
+	"github.com/sirupsen/logrus"
+)
+
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	// public keys for upstreams verifying identity assertions
+	set, err := assertion.JWKS()
+	if err != nil {
+		logrus.WithContext(r.Context()).WithError(err).Error("failed to build jwks")
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// respond with json, upstreams may cache briefly
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(assertion.JWKSMaxAge.Seconds())))
+	_ = json.NewEncoder(w).Encode(set)
+}
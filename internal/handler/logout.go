@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultLogoutRedirectParam = "next"
+
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cfg := config.Get().Logout
+	if !cfg.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	// logout changes state, so only posts from allowed pages
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !originAllowed(r, requestOrigin(r), cfg.AllowedOrigins) {
+		jsonResponse(w, http.StatusForbidden, "origin_denied", nil)
+		return
+	}
+	redirect := logoutRedirect(r, &cfg)
+
+	// revoke the session of the matching application
+	application := app.Resolve(requestHost(r))
+	fields := logrus.Fields{"application": application.Name}
+	if cookie, err := r.Cookie(application.Auth.SessionCookieName); err == nil && cookie.Value != "" {
+		// resolve the user for the log, the session is revoked either way
+		chain, _ := auth.Chain([]string{"session"})
+		identity, _ := auth.Authenticate(ctx, chain, &auth.Request{Application: application, SessionID: cookie.Value})
+		if identity != nil {
+			fields["user_id"] = identity.UserID
+		}
+		fields["session_id"] = auth.MaskSecret(cookie.Value)
+		if err := application.RevokeSession(ctx, cookie.Value); err != nil {
+			logrus.WithContext(ctx).WithFields(fields).WithError(err).Error("failed to revoke session")
+			jsonResponse(w, http.StatusServiceUnavailable, "session_store_error", nil)
+			return
+		}
+	}
+	logrus.WithContext(ctx).WithFields(fields).Info("session revoked")
+
+	// expire the cookie where django set it
+	http.SetCookie(w, &http.Cookie{
+		Name:     application.Auth.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Domain:   application.Auth.SessionCookieDomain,
+		MaxAge:   -1,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+
+	// scripts get the target as json, browsers are redirected
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		jsonResponse(w, http.StatusOK, "ok", map[string]interface{}{"redirect": redirect})
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+func logoutRedirect(r *http.Request, cfg *config.LogoutConfig) string {
+	param := cfg.RedirectParam
+	if param == "" {
+		param = defaultLogoutRedirectParam
+	}
+	if target := r.FormValue(param); target != "" && redirectAllowed(target, cfg.AllowedRedirects) {
+		return target
+	}
+	if cfg.DefaultRedirect != "" {
+		return cfg.DefaultRedirect
+	}
+	return "/"
+}
+
+func redirectAllowed(target string, allowed []string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	// same scheme and host, and below the allowed path on a segment boundary
+	for _, entry := range allowed {
+		a, err := url.Parse(entry)
+		if err != nil {
+			continue
+		}
+		if a.Scheme != u.Scheme || !strings.EqualFold(a.Host, u.Host) {
+			continue
+		}
+		// browsers resolve dot segments, so /app/../admin must not pass as /app
+		clean := path.Clean("/" + u.Path)
+		prefix := strings.TrimSuffix(a.Path, "/")
+		if prefix == "" || clean == prefix || strings.HasPrefix(clean, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
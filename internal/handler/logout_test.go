@@ -0,0 +1,40 @@
+package handler
+
+import "testing"
+
+func TestRedirectAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com/app", "https://www.example.com", "http://legacy.example.com/"}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{target: "https://app.example.com/app", want: true},
+		{target: "https://app.example.com/app/", want: true},
+		{target: "https://app.example.com/app/settings?tab=1", want: true},
+		{target: "https://APP.example.com/app/settings", want: true},
+		{target: "https://app.example.com/apple", want: false},
+		{target: "https://app.example.com/", want: false},
+		{target: "https://app.example.com/app/../admin", want: false},
+		{target: "https://app.example.com/app/%2e%2e/admin", want: false},
+		{target: "http://app.example.com/app", want: false},
+		{target: "https://www.example.com/anything/at/all", want: true},
+		{target: "https://www.example.com", want: true},
+		{target: "http://legacy.example.com/old", want: true},
+		{target: "https://evil.com/app", want: false},
+		{target: "https://app.example.com.evil.com/app", want: false},
+		{target: "https://app.example.com@evil.com/app", want: false},
+		{target: "//evil.com/app", want: false},
+		{target: "/app/settings", want: false},
+		{target: "javascript:alert(1)", want: false},
+		{target: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := redirectAllowed(tt.target, allowed); got != tt.want {
+			t.Errorf("redirectAllowed(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+	if redirectAllowed("https://app.example.com/app", nil) {
+		t.Error("redirect allowed without entries")
+	}
+}
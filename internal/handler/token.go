@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/assertion"
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/ratelimit"
+	"github.com/ovinc/zerotrust/internal/session"
+	"github.com/ovinc/zerotrust/internal/stepup"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCSRFCookieName = "csrftoken"
+	defaultCSRFHeader     = "X-CSRFToken"
+	defaultTokenRateLimit = 10
+	defaultTokenWindow    = time.Minute
+)
+
+type tokenRequest struct {
+	Audience string `json:"audience"`
+}
+
+func TokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cfg := config.Get().TokenExchange
+	if !cfg.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	csrfCookieName := cfg.CSRFCookieName
+	if csrfCookieName == "" {
+		csrfCookieName = defaultCSRFCookieName
+	}
+	csrfHeader := cfg.CSRFHeader
+	if csrfHeader == "" {
+		csrfHeader = defaultCSRFHeader
+	}
+
+	// only pages of allowed origins may exchange their session
+	origin := requestOrigin(r)
+	if !originAllowed(r, origin, cfg.AllowedOrigins) {
+		tokenError(w, r, http.StatusForbidden, "origin_denied", "")
+		return
+	}
+	setCORSHeaders(w, origin, []string{http.MethodPost}, []string{"Content-Type", csrfHeader})
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// the csrf header must match the csrftoken cookie
+	csrfCookie, err := r.Cookie(csrfCookieName)
+	if err != nil || !session.CheckCSRF(csrfCookie.Value, r.Header.Get(csrfHeader)) {
+		tokenError(w, r, http.StatusForbidden, "csrf_failed", "")
+		return
+	}
+
+	// audience must be on the allow list
+	var body tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		tokenError(w, r, http.StatusBadRequest, "bad_request", "")
+		return
+	}
+	if !slices.Contains(cfg.Audiences, body.Audience) {
+		tokenError(w, r, http.StatusBadRequest, "invalid_audience", "")
+		return
+	}
+
+	// session cookie of the application serving this page
+	host := requestHost(r)
+	application := app.Resolve(host)
+	sessionCookie, err := r.Cookie(application.Auth.SessionCookieName)
+	if err != nil || sessionCookie.Value == "" {
+		tokenError(w, r, http.StatusUnauthorized, auth.ReasonMissingCredentials, "")
+		return
+	}
+	sessionID := sessionCookie.Value
+
+	// limit exchanges per session
+	if retryAfter, limited := tokenRateLimited(r, application, sessionID); limited {
+		w.Header().Set("Retry-After", ratelimit.Seconds(retryAfter))
+		tokenError(w, r, http.StatusTooManyRequests, "rate_limited", sessionID)
+		return
+	}
+
+	// the session must pass every check forward auth applies, tokens are never bound to the client
+	req := newSessionRequest(r, host, sessionID)
+	decision := Authorize(ctx, req)
+	logDecision(ctx, req, decision)
+	if decision.Status != http.StatusOK {
+		if decision.Status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", ratelimit.Seconds(decision.RetryAfter))
+		}
+		tokenError(w, r, decision.Status, decision.Reason, sessionID)
+		return
+	}
+	identity := decision.Identity
+
+	// sign access token for the audience, auth_time lets routes with max_auth_age check the login behind it,
+	// so it is never taken from the last login cache
+	authTime, _, err := stepup.AuthTime(ctx, identity, 0)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("failed to read login time")
+	}
+	token, ttl, err := assertion.AccessToken(&assertion.Subject{
+		UserID:        identity.UserID,
+		Backend:       identity.Backend,
+		Authenticator: identity.Authenticator,
+		Attributes:    identity.Attributes,
+		AuthTime:      authTime,
+	}, body.Audience)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to sign access token")
+		jsonResponse(w, http.StatusInternalServerError, "internal_server_error", nil)
+		return
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"application": application.Name,
+		"user_id":     identity.UserID,
+		"session_id":  auth.MaskSecret(sessionID),
+		"audience":    body.Audience,
+	}).Info("token issued")
+	w.Header().Set("Cache-Control", "no-store")
+	jsonResponse(w, http.StatusOK, "ok", map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+		"audience":     body.Audience,
+	})
+}
+
+func tokenRateLimited(r *http.Request, application *app.Application, sessionID string) (time.Duration, bool) {
+	cfg := config.Get().TokenExchange
+	limit := cfg.RateLimit
+	if limit <= 0 {
+		limit = defaultTokenRateLimit
+	}
+	window := cfg.RateWindow
+	if window <= 0 {
+		window = defaultTokenWindow
+	}
+
+	// count by session hash, a store outage does not block exchanges
+	sum := sha256.Sum256([]byte(sessionID))
+	count, remaining, err := application.Store.Incr(r.Context(), "zerotrust:token_exchange:"+hex.EncodeToString(sum[:]), window)
+	if err != nil {
+		logrus.WithContext(r.Context()).WithError(err).Warn("failed to count token exchanges")
+		return 0, false
+	}
+	return remaining, count > int64(limit)
+}
+
+func tokenError(w http.ResponseWriter, r *http.Request, status int, reason, sessionID string) {
+	fields := logrus.Fields{"origin": requestOrigin(r), "reason": reason}
+	if sessionID != "" {
+		fields["session_id"] = auth.MaskSecret(sessionID)
+	}
+	logrus.WithContext(r.Context()).WithFields(fields).Warn("token exchange denied")
+	jsonResponse(w, status, reason, nil)
+}
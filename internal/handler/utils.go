@@ -9,151 +9,124 @@ import (
 	"net/url"
 	"strings"
 
-	"github.com/ovinc/zerotrust/internal/config"
-	"github.com/ovinc/zerotrust/internal/session"
-	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/ratelimit"
+	"github.com/ovinc/zerotrust/internal/stepup"
 	"github.com/sirupsen/logrus"
 )
 
-func maskSessionID(sessionID string) string {
-	// return masked placeholder for short session ids
-	if len(sessionID) <= 8 {
-		return "****"
+func unauthorizedResponse(ctx context.Context, w http.ResponseWriter, req *VerifyRequest, reason string) {
+	auth := app.Resolve(req.Host).Auth
+
+	// unauthorized page offers a login button
+	loginURL := fmt.Sprintf(
+		"%s?%s=%s",
+		auth.LoginUrl,
+		auth.LoginRedirectParam,
+		url.QueryEscape(fmt.Sprintf("%s://%s%s", req.Protocol, req.Host, req.Path)),
+	)
+	// hint the login page to ask for credentials although the session is still valid
+	if reason == stepup.ReasonReauthRequired {
+		loginURL += "&" + url.QueryEscape(stepup.Param()) + "=1"
 	}
-	// mask middle part for security
-	return sessionID[:4] + "****" + sessionID[len(sessionID)-4:]
+	errorResponse(ctx, w, req, http.StatusUnauthorized, reason, loginURL)
 }
 
-func unauthorizedResponse(ctx context.Context, w http.ResponseWriter, req *VerifyRequest) {
-	cfg := config.Get()
+func forbiddenResponse(ctx context.Context, w http.ResponseWriter, req *VerifyRequest) {
+	// logging in again does not help, so no login button
+	errorResponse(ctx, w, req, http.StatusForbidden, "", "")
+}
 
+func errorResponse(ctx context.Context, w http.ResponseWriter, req *VerifyRequest, status int, reason, loginURL string) {
 	// response html
 	if strings.Contains(req.Accept, "text/html") {
 		// build data
 		initData := map[string]interface{}{
-			"url": fmt.Sprintf(
-				"%s?%s=%s",
-				cfg.Auth.LoginUrl,
-				cfg.Auth.LoginRedirectParam,
-				url.QueryEscape(fmt.Sprintf("%s://%s%s", req.Protocol, req.Host, req.Path)),
-			),
+			"title":               "身份验证失败",
+			"message":             "无法验证您的身份信息，请登录后重试",
+			"url":                 loginURL,
 			"urlDisplayStyle":     "inline-block",
 			"traceID":             req.RequestID,
 			"traceIdDisplayStyle": "block",
 		}
+		if status == http.StatusForbidden {
+			initData["title"] = "访问被拒绝"
+			initData["message"] = "您没有权限访问此资源"
+		}
+		if status == http.StatusTooManyRequests {
+			initData["title"] = "请求过于频繁"
+			initData["message"] = "请求次数过多，请稍后再试"
+		}
+		if reason == stepup.ReasonReauthRequired {
+			initData["title"] = "需要重新登录"
+			initData["message"] = "访问此资源需要近期登录，请重新登录后继续"
+		}
+		if loginURL == "" {
+			initData["urlDisplayStyle"] = "none"
+		}
 		if req.RequestID == "" {
 			initData["traceIdDisplayStyle"] = "none"
 		}
 		// parse template
 		var buf bytes.Buffer
 		if err := htmlTemplate.Execute(&buf, initData); err != nil {
-			logrus.WithContext(ctx).WithError(err).Error("[UnauthorizedResponse] failed to execute html template")
+			logrus.WithContext(ctx).WithError(err).Error("[ErrorResponse] failed to execute html template")
 			return
 		}
 		// write response
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.WriteHeader(http.StatusUnauthorized)
+		w.WriteHeader(status)
 		_, _ = w.Write(buf.Bytes())
 		return
 	}
 
 	// response json
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusUnauthorized)
-	data := map[string]interface{}{"code": 401, "error": "unauthorized", "message": "unauthorized", "data": nil}
-	_ = json.NewEncoder(w).Encode(data)
+	message := strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	var data interface{}
+	if reason == stepup.ReasonReauthRequired {
+		// api clients get the login url to send the user to
+		data = map[string]string{"reason": reason, "login_url": loginURL}
+	}
+	jsonResponse(w, status, message, data)
 }
 
-func doAuth(ctx context.Context, w http.ResponseWriter, req *VerifyRequest) {
-	cfg := config.Get()
-
-	var (
-		result    string
-		reason    string
-		userID    string
-		sessionID string
-		logErr    error
-	)
-	defer func() {
-		fields := logrus.Fields{
-			"client_ip":  req.ClientIP,
-			"method":     req.Method,
-			"protocol":   req.Protocol,
-			"host":       req.Host,
-			"path":       req.Path,
-			"request_id": req.RequestID,
-			"user_agent": req.UserAgent,
-			"referer":    req.Referer,
-			"user_id":    "",
-			"session_id": "",
-			"reason":     "",
-		}
-		if userID != "" {
-			fields["user_id"] = userID
-		}
-		if sessionID != "" {
-			fields["session_id"] = maskSessionID(sessionID)
-		}
-		if reason != "" {
-			fields["reason"] = reason
-		}
-
-		entry := logrus.WithContext(ctx).WithFields(fields)
-		if logErr != nil {
-			entry.WithError(logErr).Warn(result)
-			return
-		}
-		entry.Info(result)
-	}()
-
-	// check methods
-	skipVerify := true
-	reqMethod := strings.ToLower(req.Method)
-	for _, m := range cfg.Auth.VerifyMethods {
-		if strings.ToLower(m) == reqMethod {
-			skipVerify = false
-			break
-		}
-	}
-	if skipVerify {
-		result = "request skipped"
-		reason = "method_not_verified"
-		w.WriteHeader(http.StatusOK)
-		return
+func jsonResponse(w http.ResponseWriter, status int, message string, data interface{}) {
+	// error carries the message only for failures
+	var errValue interface{}
+	if status >= http.StatusBadRequest {
+		errValue = message
 	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": status, "error": errValue, "message": message, "data": data})
+}
 
-	// check if session id is provided
-	if req.SessionID == "" {
-		result = "request unauthorized"
-		reason = "missing_session"
-		unauthorizedResponse(ctx, w, req)
-		return
+func requestHost(r *http.Request) string {
+	// called through the proxy on the application domain, or directly
+	if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+		return host
 	}
+	return r.Host
+}
 
-	// get session data from redis
-	sessionData, err := store.GetSession(ctx, req.SessionID)
-	if err != nil {
-		result = "request unauthorized"
-		reason = "session_store_error"
-		logErr = err
-		unauthorizedResponse(ctx, w, req)
-		return
+func doAuth(ctx context.Context, w http.ResponseWriter, req *VerifyRequest) {
+	// make the decision, then log and respond
+	decision := Authorize(ctx, req)
+	logDecision(ctx, req, decision)
+	recordDecision(req, decision)
+	for name, values := range decision.Headers {
+		w.Header()[name] = values
 	}
 
-	// parse django session to extract user info
-	userInfo, err := session.ParseDjangoSession(ctx, []byte(sessionData))
-	if err != nil {
-		result = "request unauthorized"
-		reason = "session_parse_error"
-		logErr = err
-		unauthorizedResponse(ctx, w, req)
-		return
+	switch decision.Status {
+	case http.StatusUnauthorized:
+		unauthorizedResponse(ctx, w, req, decision.Reason)
+	case http.StatusForbidden:
+		forbiddenResponse(ctx, w, req)
+	case http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", ratelimit.Seconds(decision.RetryAfter))
+		errorResponse(ctx, w, req, http.StatusTooManyRequests, "", "")
+	default:
+		w.WriteHeader(decision.Status)
 	}
-
-	result = "request authorized"
-	userID = userInfo.UserID
-	sessionID = req.SessionID
-
-	// return success response with user id
-	w.WriteHeader(http.StatusOK)
 }
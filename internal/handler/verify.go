@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"net/http"
 
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/clientip"
 	"github.com/ovinc/zerotrust/internal/config"
 	"github.com/sirupsen/logrus"
 )
@@ -19,6 +22,19 @@ type VerifyRequest struct {
 	Referer   string `json:"referer"`
 	Accept    string `json:"accept"`
 	RequestID string `json:"request_id"`
+
+	Authorization string            `json:"authorization"`
+	APIKey        string            `json:"api_key"`
+	Headers       map[string]string `json:"headers"`
+
+	// connection details, never taken from the body
+	RemoteAddr       string              `json:"-"`
+	PeerCertificates []*x509.Certificate `json:"-"`
+
+	// set for endpoints that act on the session cookie itself
+	sessionOnly bool
+	// set for endpoints that only report the decision, nothing is bound, counted or extended
+	readOnly bool
 }
 
 func VerifyHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,18 +53,24 @@ func VerifyHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
+	req.setConnection(r)
+	req.ClientIP = clientip.FromList(req.ClientIP)
 
 	// perform authentication
 	doAuth(ctx, w, &req)
 }
 
 func ForwardAuthHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	// perform authentication
+	doAuth(r.Context(), w, NewForwardAuthRequest(r))
+}
+
+func NewForwardAuthRequest(r *http.Request) *VerifyRequest {
 	cfg := config.Get()
 
 	// load info from headers
-	req := VerifyRequest{
-		ClientIP:  r.Header.Get(cfg.Auth.ClientIPHeader),
+	req := &VerifyRequest{
+		ClientIP:  clientip.FromRequest(r),
 		Method:    r.Header.Get("X-Forwarded-Method"),
 		Protocol:  r.Header.Get("X-Forwarded-Proto"),
 		Host:      r.Header.Get("X-Forwarded-Host"),
@@ -57,13 +79,62 @@ func ForwardAuthHandler(w http.ResponseWriter, r *http.Request) {
 		Referer:   r.Header.Get("Referer"),
 		Accept:    r.Header.Get("Accept"),
 		RequestID: r.Header.Get(cfg.Auth.TraceIDHeader),
+
+		Authorization: r.Header.Get("Authorization"),
+		Headers:       make(map[string]string, len(r.Header)),
+	}
+
+	// keep remaining headers for authenticators that read their own
+	for name := range r.Header {
+		req.Headers[name] = r.Header.Get(name)
 	}
 
-	// get session id from cookies
-	if cookie, err := r.Cookie(cfg.Auth.SessionCookieName); err == nil {
+	req.setConnection(r)
+
+	// get api key from the configured header
+	if cfg.APIKey.Header != "" {
+		req.APIKey = r.Header.Get(cfg.APIKey.Header)
+	}
+
+	// get session id from the cookie of the matching application
+	if cookie, err := r.Cookie(app.Resolve(req.Host).Auth.SessionCookieName); err == nil {
 		req.SessionID = cookie.Value
 	}
 
-	// perform authentication
-	doAuth(ctx, w, &req)
+	return req
+}
+
+func newSessionRequest(r *http.Request, host, sessionID string) *VerifyRequest {
+	// endpoint requests go through the same checks as the pages they serve
+	protocol := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		protocol = "https"
+	}
+	req := &VerifyRequest{
+		ClientIP:  clientip.FromRequest(r),
+		SessionID: sessionID,
+		Method:    r.Method,
+		Protocol:  protocol,
+		Host:      host,
+		Path:      r.URL.Path,
+		UserAgent: r.Header.Get("User-Agent"),
+		Referer:   r.Header.Get("Referer"),
+		Accept:    r.Header.Get("Accept"),
+		RequestID: r.Header.Get(config.Get().Auth.TraceIDHeader),
+		Headers:   make(map[string]string, len(r.Header)),
+
+		sessionOnly: true,
+	}
+	for name := range r.Header {
+		req.Headers[name] = r.Header.Get(name)
+	}
+	req.setConnection(r)
+	return req
+}
+
+func (req *VerifyRequest) setConnection(r *http.Request) {
+	req.RemoteAddr = r.RemoteAddr
+	if r.TLS != nil {
+		req.PeerCertificates = r.TLS.PeerCertificates
+	}
 }
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func WhoamiHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cfg := config.Get().Whoami
+	if !cfg.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	// credentialed cors for allowed frontends, other origins get no cors headers
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if originAllowed(r, origin, cfg.AllowedOrigins) {
+			setCORSHeaders(w, origin, []string{http.MethodGet}, nil)
+		} else if r.Method == http.MethodOptions {
+			jsonResponse(w, http.StatusForbidden, "origin_denied", nil)
+			return
+		}
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	// session cookie of the matching application, like forward auth
+	host := requestHost(r)
+	application := app.Resolve(host)
+	var sessionID string
+	if cookie, err := r.Cookie(application.Auth.SessionCookieName); err == nil {
+		sessionID = cookie.Value
+	}
+	// a session forward auth would reject is not reported either, looking does not count as using it
+	req := newSessionRequest(r, host, sessionID)
+	req.readOnly = true
+	decision := Authorize(ctx, req)
+	logDecision(ctx, req, decision)
+	if decision.Status != http.StatusOK {
+		jsonResponse(w, decision.Status, strings.ToLower(strings.ReplaceAll(http.StatusText(decision.Status), " ", "_")), nil)
+		return
+	}
+	identity := decision.Identity
+
+	// session expiry from the key ttl, null when the key never expires
+	var expiresAt interface{}
+	ttl, err := application.Store.SessionTTL(ctx, sessionID)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("failed to read session ttl")
+	} else if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+	}
+
+	jsonResponse(w, http.StatusOK, "ok", map[string]interface{}{
+		"user_id":     identity.UserID,
+		"backend":     identity.Backend,
+		"application": application.Name,
+		"attributes":  identity.Attributes,
+		"expires_at":  expiresAt,
+	})
+}
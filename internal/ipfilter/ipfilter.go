@@ -0,0 +1,105 @@
+package ipfilter
+
+import (
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonDenied = "ip_denied"
+
+const defaultReloadInterval = 30 * time.Second
+
+type filter struct {
+	allow *list
+	deny  *list
+}
+
+var (
+	global *filter
+	routes map[*config.RouteConfig]*filter
+)
+
+func Init() {
+	cfg := config.Get()
+
+	// global lists plus one pair per route that configures any
+	var all []*filter
+	var err error
+	if global, err = newFilter(&cfg.IPFilter); err != nil {
+		logrus.WithError(err).Fatal("invalid ip_filter")
+	}
+	all = append(all, global)
+	routes = map[*config.RouteConfig]*filter{}
+	for i := range cfg.Routes {
+		route := &cfg.Routes[i]
+		if route.IPFilter == nil {
+			continue
+		}
+		f, err := newFilter(route.IPFilter)
+		if err != nil {
+			logrus.WithError(err).WithField("route", route.Name).Fatal("invalid route ip_filter")
+		}
+		routes[route] = f
+		all = append(all, f)
+	}
+
+	// reload list files in background
+	interval := cfg.IPFilter.ReloadInterval
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, f := range all {
+				f.reload()
+			}
+		}
+	}()
+}
+
+func newFilter(cfg *config.IPFilterConfig) (*filter, error) {
+	allow, err := newList(cfg.Allow, cfg.AllowFiles)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := newList(cfg.Deny, cfg.DenyFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &filter{allow: allow, deny: deny}, nil
+}
+
+func (f *filter) reload() {
+	for _, l := range []*list{f.allow, f.deny} {
+		changed, err := l.load()
+		if err != nil {
+			logrus.WithError(err).Warn("failed to reload ip list, keeping previous entries")
+			continue
+		}
+		if changed {
+			logrus.WithField("files", l.files).Info("ip list reloaded")
+		}
+	}
+}
+
+func (f *filter) allowed(ip string) bool {
+	// deny wins, a configured allow list must contain the ip
+	if f.deny.configured() && f.deny.contains(ip) {
+		return false
+	}
+	return !f.allow.configured() || f.allow.contains(ip)
+}
+
+func Allowed(route *config.RouteConfig, ip string) bool {
+	if global != nil && !global.allowed(ip) {
+		return false
+	}
+	if f, ok := routes[route]; ok && !f.allowed(ip) {
+		return false
+	}
+	return true
+}
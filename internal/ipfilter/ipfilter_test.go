@@ -0,0 +1,138 @@
+package ipfilter
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func TestTrie(t *testing.T) {
+	tr := newTrie()
+	for _, entry := range []string{"10.0.0.0/8", "192.168.1.7", "172.16.0.0/12", "172.16.5.0/24", "2001:db8::/32", "::ffff:198.51.100.0/120", "0.0.0.0/32", "fe80::1%eth0"} {
+		if err := insert(tr, entry); err != nil {
+			t.Fatalf("insert(%q) = %v", entry, err)
+		}
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.0", true},
+		{"10.255.255.255", true},
+		{"11.0.0.0", false},
+		{"9.255.255.255", false},
+		{"192.168.1.7", true},
+		{"192.168.1.6", false},
+		{"192.168.1.8", false},
+		{"172.16.5.9", true},
+		{"172.31.255.255", true},
+		{"172.32.0.0", false},
+		{"198.51.100.42", true},
+		{"198.51.101.1", false},
+		{"0.0.0.0", true},
+		{"0.0.0.1", false},
+		{"2001:db8::1", true},
+		{"2001:db8:ffff::", true},
+		{"2001:db9::1", false},
+		{"::a00:1", false},
+		{"fe80::1", true},
+		{"fe80::2", false},
+	}
+	for _, tt := range tests {
+		if got := tr.contains(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("contains(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestTrieShorterPrefixWins(t *testing.T) {
+	// a wider prefix inserted after a narrower one, or before it, covers the whole range
+	for _, entries := range [][]string{{"10.1.2.0/24", "10.0.0.0/8"}, {"10.0.0.0/8", "10.1.2.0/24"}} {
+		tr := newTrie()
+		for _, entry := range entries {
+			if err := insert(tr, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !tr.contains(netip.MustParseAddr("10.200.0.1")) {
+			t.Errorf("%v: 10.200.0.1 not contained", entries)
+		}
+	}
+
+	// the empty prefix matches every address of its family only
+	tr := newTrie()
+	if err := insert(tr, "0.0.0.0/0"); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.contains(netip.MustParseAddr("203.0.113.1")) || tr.contains(netip.MustParseAddr("2001:db8::1")) {
+		t.Error("0.0.0.0/0 must match all ipv4 and no ipv6 addresses")
+	}
+}
+
+func TestInsertInvalid(t *testing.T) {
+	for _, entry := range []string{"", "10.0.0.0/33", "example.com", "10.0.0", "::ffff:10.0.0.0/8"} {
+		if err := insert(newTrie(), entry); err == nil {
+			t.Errorf("insert(%q) succeeded", entry)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	dir := t.TempDir()
+	allowFile := filepath.Join(dir, "allow.txt")
+	if err := os.WriteFile(allowFile, []byte("# office\n203.0.113.0/24 # vpn\n\n2001:db8::/48\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := newFilter(&config.IPFilterConfig{
+		Allow:      []string{"10.0.0.0/8"},
+		AllowFiles: []string{allowFile},
+		Deny:       []string{"10.6.6.6", "203.0.113.66"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"10.6.6.6", false},
+		{"203.0.113.5", true},
+		{"203.0.113.66", false},
+		{"2001:db8::1", true},
+		{"2001:db8:1::1", false},
+		{"198.51.100.1", false},
+		{"", false},
+		{"garbage", false},
+	}
+	for _, tt := range tests {
+		if got := f.allowed(tt.ip); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	// without an allow list only the deny list applies
+	f, err = newFilter(&config.IPFilterConfig{Deny: []string{"198.51.100.0/24"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.allowed("198.51.100.1") || !f.allowed("203.0.113.5") {
+		t.Error("deny only filter")
+	}
+}
+
+func TestListFileError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deny.txt")
+	if err := os.WriteFile(file, []byte("10.0.0.1\nnot-an-ip\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newList(nil, []string{file}); err == nil || !strings.HasPrefix(err.Error(), file+":2: ") {
+		t.Fatalf("newList() = %v", err)
+	}
+}
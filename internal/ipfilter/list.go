@@ -0,0 +1,95 @@
+package ipfilter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/clientip"
+)
+
+type list struct {
+	entries []string
+	files   []string
+
+	prefixes atomic.Pointer[trie]
+	modTimes map[string]time.Time
+}
+
+func newList(entries, files []string) (*list, error) {
+	l := &list{entries: entries, files: files, modTimes: map[string]time.Time{}}
+	if _, err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *list) configured() bool {
+	return len(l.entries) > 0 || len(l.files) > 0
+}
+
+func (l *list) contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && l.prefixes.Load().contains(addr)
+}
+
+func (l *list) load() (bool, error) {
+	changed := l.prefixes.Load() == nil
+	modTimes := make(map[string]time.Time, len(l.files))
+	for _, file := range l.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		modTimes[file] = info.ModTime()
+		if !info.ModTime().Equal(l.modTimes[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	// a broken file is reported once per change, not on every tick
+	l.modTimes = modTimes
+
+	// inline entries first, then one entry per file line, # starts a comment
+	t := newTrie()
+	for _, entry := range l.entries {
+		if err := insert(t, entry); err != nil {
+			return false, err
+		}
+	}
+	for _, file := range l.files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			entry, _, _ := strings.Cut(scanner.Text(), "#")
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			if err := insert(t, entry); err != nil {
+				return false, fmt.Errorf("%s:%d: %w", file, line, err)
+			}
+		}
+	}
+
+	l.prefixes.Store(t)
+	return true, nil
+}
+
+func insert(t *trie, entry string) error {
+	prefix, err := clientip.ParsePrefix(entry)
+	if err != nil {
+		return err
+	}
+	t.insert(prefix)
+	return nil
+}
@@ -0,0 +1,57 @@
+package ipfilter
+
+import "net/netip"
+
+type trie struct {
+	v4 *node
+	v6 *node
+}
+
+type node struct {
+	children [2]*node
+	terminal bool
+}
+
+func newTrie() *trie {
+	return &trie{v4: &node{}, v6: &node{}}
+}
+
+func (t *trie) root(addr netip.Addr) *node {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+func (t *trie) insert(prefix netip.Prefix) {
+	n := t.root(prefix.Addr())
+	bytes := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits() && !n.terminal; i++ {
+		bit := bytes[i/8] >> (7 - i%8) & 1
+		if n.children[bit] == nil {
+			n.children[bit] = &node{}
+		}
+		n = n.children[bit]
+	}
+
+	// a shorter prefix covers everything below it
+	n.terminal = true
+	n.children = [2]*node{}
+}
+
+func (t *trie) contains(addr netip.Addr) bool {
+	n := t.root(addr)
+	bytes := addr.AsSlice()
+	for i := 0; ; i++ {
+		if n.terminal {
+			return true
+		}
+		if i == len(bytes)*8 {
+			return false
+		}
+		n = n.children[bytes[i/8]>>(7-i%8)&1]
+		if n == nil {
+			return false
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrSignature        = errors.New("invalid token signature")
+	ErrUnsupportedAlg   = errors.New("unsupported token algorithm")
+	ErrExpired          = errors.New("token expired")
+	ErrNotYetValid      = errors.New("token not yet valid")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+	ErrMissingExpiresAt = errors.New("token has no expiry")
+	ErrMissingSubject   = errors.New("token has no user claim")
+)
+
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+type Claims map[string]interface{}
+
+type Token struct {
+	Header       Header
+	Claims       Claims
+	signingInput string
+	signature    []byte
+}
+
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	// decode header and claims without verifying
+	token := &Token{signingInput: parts[0] + "." + parts[1]}
+	if err := decodeSegment(parts[0], &token.Header); err != nil {
+		return nil, err
+	}
+	if err := decodeSegment(parts[1], &token.Claims); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	token.signature = signature
+	return token, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
+
+func (t *Token) Verify(key Key) error {
+	// a jwk restricted to one algorithm only verifies tokens of that algorithm
+	if key.Alg != "" && key.Alg != t.Header.Alg {
+		return ErrUnsupportedAlg
+	}
+
+	// algorithm family and curve must match the key type before anything is hashed
+	if !algorithmFits(t.Header.Alg, key.Public) {
+		return ErrUnsupportedAlg
+	}
+	hash, err := hashFor(t.Header.Alg)
+	if err != nil {
+		return err
+	}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		digest := digestOf(hash, t.signingInput)
+		if strings.HasPrefix(t.Header.Alg, "PS") {
+			err = rsa.VerifyPSS(pub, hash, digest, t.signature, nil)
+		} else {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, t.signature)
+		}
+		if err != nil {
+			return ErrSignature
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return ErrSignature
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(pub, digestOf(hash, t.signingInput), r, s) {
+			return ErrSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, []byte(t.signingInput), t.signature) {
+			return ErrSignature
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
+
+func algorithmFits(alg string, key crypto.PublicKey) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+			return true
+		}
+		return false
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		// one algorithm per curve
+		return alg == AlgorithmFor(key)
+	default:
+		return false
+	}
+}
+
+func (t *Token) ValidateTime(now time.Time, skew time.Duration, requireExp bool) error {
+	// exp and nbf are checked with clock skew in both directions
+	exp, hasExp := t.Claims.Time("exp")
+	if !hasExp && requireExp {
+		return ErrMissingExpiresAt
+	}
+	if hasExp && !now.Before(exp.Add(skew)) {
+		return ErrExpired
+	}
+	if nbf, ok := t.Claims.Time("nbf"); ok && now.Add(skew).Before(nbf) {
+		return ErrNotYetValid
+	}
+	return nil
+}
+
+func (c Claims) String(name string) string {
+	switch v := c[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
+
+func (c Claims) Strings(name string) []string {
+	// claims may hold a single string or a list of strings
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case float64:
+		return []string{fmt.Sprintf("%.0f", v)}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func (c Claims) Time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+func Sign(claims Claims, key crypto.Signer, kid string) (string, error) {
+	// pick algorithm from the key type
+	var alg string
+	switch pub := key.Public().(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve.Params().BitSize {
+		case 256:
+			alg = "ES256"
+		case 384:
+			alg = "ES384"
+		case 521:
+			alg = "ES512"
+		default:
+			return "", ErrUnsupportedKey
+		}
+	case ed25519.PublicKey:
+		alg = "EdDSA"
+	case *rsa.PublicKey:
+		alg = "RS256"
+	default:
+		return "", ErrUnsupportedKey
+	}
+
+	// encode header and claims
+	header, err := json.Marshal(Header{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	// sign, ecdsa signatures use the fixed size r||s form
+	var signature []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		hash, _ := hashFor(alg)
+		r, s, err := ecdsa.Sign(rand.Reader, k, digestOf(hash, signingInput))
+		if err != nil {
+			return "", err
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signingInput))
+	default:
+		hash, _ := hashFor(alg)
+		signature, err = key.Sign(rand.Reader, digestOf(hash, signingInput), hash)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func AlgorithmFor(key crypto.PublicKey) string {
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		return map[int]string{256: "ES256", 384: "ES384", 521: "ES512"}[pub.Curve.Params().BitSize]
+	case ed25519.PublicKey:
+		return "EdDSA"
+	case *rsa.PublicKey:
+		return "RS256"
+	default:
+		return ""
+	}
+}
+
+func hashFor(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, nil
+	case "EdDSA":
+		return 0, nil
+	default:
+		return 0, ErrUnsupportedAlg
+	}
+}
+
+func digestOf(hash crypto.Hash, input string) []byte {
+	h := hash.New()
+	h.Write([]byte(input))
+	return h.Sum(nil)
+}
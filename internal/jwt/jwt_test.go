@@ -0,0 +1,211 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testKeys struct {
+	es256, es384, ed, rsa crypto.Signer
+	set                   *KeySet
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	keys := &testKeys{}
+	var err error
+	if keys.es256, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if keys.es384, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if _, keys.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if keys.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	// local jwks file with one key per algorithm, the rsa key is pinned to RS256
+	var set JWKS
+	for _, k := range []struct {
+		kid, alg string
+		key      crypto.Signer
+	}{{"es256", "", keys.es256}, {"es384", "", keys.es384}, {"ed", "", keys.ed}, {"rsa", "RS256", keys.rsa}} {
+		jwk, err := NewJWK(k.key.Public(), k.kid, k.alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys.set = NewKeySet(path, "", 0)
+	if err := keys.set.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func sign(t *testing.T, key crypto.Signer, kid string) string {
+	t.Helper()
+	raw, err := Sign(Claims{"sub": "42"}, key, kid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func withHeader(t *testing.T, raw string, header Header) string {
+	return withSegment(t, raw, 0, header)
+}
+
+func withClaims(t *testing.T, raw string, claims Claims) string {
+	return withSegment(t, raw, 1, claims)
+}
+
+func withSegment(t *testing.T, raw string, index int, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(raw, ".")
+	parts[index] = base64.RawURLEncoding.EncodeToString(data)
+	return strings.Join(parts, ".")
+}
+
+func TestVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	es256 := sign(t, keys.es256, "es256")
+
+	tests := []struct {
+		name string
+		raw  string
+		kid  string
+		want error
+	}{
+		{name: "es256", raw: es256, kid: "es256"},
+		{name: "es384", raw: sign(t, keys.es384, "es384"), kid: "es384"},
+		{name: "eddsa", raw: sign(t, keys.ed, "ed"), kid: "ed"},
+		{name: "rs256", raw: sign(t, keys.rsa, "rsa"), kid: "rsa"},
+		{name: "signed by another key", raw: sign(t, keys.es256, "es384"), kid: "es384", want: ErrUnsupportedAlg},
+		{name: "signed by another key of the same curve", raw: es256, kid: "es256-other", want: ErrSignature},
+		{name: "tampered claims", raw: withClaims(t, es256, Claims{"sub": "1"}), kid: "es256", want: ErrSignature},
+		{name: "none", raw: withHeader(t, es256, Header{Alg: "none"}), kid: "es256", want: ErrUnsupportedAlg},
+		{name: "hmac with public key", raw: withHeader(t, sign(t, keys.rsa, "rsa"), Header{Alg: "HS256"}), kid: "rsa", want: ErrUnsupportedAlg},
+		{name: "pinned algorithm", raw: withHeader(t, sign(t, keys.rsa, "rsa"), Header{Alg: "PS256"}), kid: "rsa", want: ErrUnsupportedAlg},
+		{name: "curve mismatch", raw: withHeader(t, es256, Header{Alg: "ES384"}), kid: "es256", want: ErrUnsupportedAlg},
+	}
+
+	// a second p-256 key under its own kid to check signatures are really compared
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.set.keys["es256-other"] = Key{Public: other.Public()}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := Parse(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := keys.set.Key(context.Background(), tt.kid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := token.Verify(key); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlgorithmFits(t *testing.T) {
+	keys := newTestKeys(t)
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		alg  string
+		key  crypto.PublicKey
+		want bool
+	}{
+		{"RS256", keys.rsa.Public(), true},
+		{"PS512", keys.rsa.Public(), true},
+		{"ES256", keys.rsa.Public(), false},
+		{"HS256", keys.rsa.Public(), false},
+		{"ES256", keys.es256.Public(), true},
+		{"ES384", keys.es256.Public(), false},
+		{"ES384", keys.es384.Public(), true},
+		{"ES512", p521.Public(), true},
+		{"EdDSA", keys.ed.Public(), true},
+		{"ES256", keys.ed.Public(), false},
+		{"none", keys.ed.Public(), false},
+		{"RS256", nil, false},
+	}
+	for _, tt := range tests {
+		if got := algorithmFits(tt.alg, tt.key); got != tt.want {
+			t.Errorf("algorithmFits(%q, %T) = %v, want %v", tt.alg, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeySetUnknownKid(t *testing.T) {
+	keys := newTestKeys(t)
+	if _, err := keys.set.Key(context.Background(), "missing"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Key() = %v, want %v", err, ErrUnknownKey)
+	}
+
+	// remote sets refresh for an unknown kid at most once a minute
+	data, err := os.ReadFile(keys.set.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	remote := NewKeySet("", server.URL, 0)
+	if err := remote.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if _, err := remote.Key(context.Background(), "missing"); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("Key() = %v, want %v", err, ErrUnknownKey)
+		}
+	}
+	if _, err := remote.Key(context.Background(), "ed"); err != nil {
+		t.Fatalf("Key() = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("fetched %d times, want 2", got)
+	}
+}
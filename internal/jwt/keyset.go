@@ -0,0 +1,172 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+const minRefreshInterval = time.Minute
+
+type KeySet struct {
+	file     string
+	url      string
+	client   *http.Client
+	interval time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]Key
+	fileModTime time.Time
+
+	refreshMu   sync.Mutex
+	lastAttempt time.Time
+}
+
+func NewKeySet(file, url string, interval time.Duration) *KeySet {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &KeySet{
+		file:     file,
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: interval,
+		keys:     map[string]Key{},
+	}
+}
+
+func (k *KeySet) Start(ctx context.Context) error {
+	// initial load must succeed so misconfiguration is caught at startup
+	if err := k.Refresh(ctx); err != nil {
+		return err
+	}
+
+	// refresh periodically in background
+	go func() {
+		ticker := time.NewTicker(k.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.Refresh(ctx); err != nil {
+					logrus.WithContext(ctx).WithError(err).Warn("[KeySet] failed to refresh jwks")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (k *KeySet) Refresh(ctx context.Context) error {
+	// local file is only reloaded when it changed
+	if k.file != "" {
+		info, err := os.Stat(k.file)
+		if err != nil {
+			return err
+		}
+		k.mu.RLock()
+		unchanged := info.ModTime().Equal(k.fileModTime)
+		k.mu.RUnlock()
+		if unchanged {
+			return nil
+		}
+		data, err := os.ReadFile(k.file)
+		if err != nil {
+			return err
+		}
+		return k.setKeys(data, info.ModTime())
+	}
+
+	// remote jwks is fetched every time
+	data, err := k.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	return k.setKeys(data, time.Time{})
+}
+
+func (k *KeySet) setKeys(data []byte, modTime time.Time) error {
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	// replace keys atomically
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.fileModTime = modTime
+	return nil
+}
+
+func (k *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected jwks status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k *KeySet) Key(ctx context.Context, kid string) (Key, error) {
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+
+	if k.url == "" {
+		return Key{}, ErrUnknownKey
+	}
+
+	// unknown kid usually means the issuer rotated keys, one refresh at a time and at most
+	// once a minute whether it worked or not, so random kids cannot fan out to the issuer
+	k.refreshMu.Lock()
+	defer k.refreshMu.Unlock()
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(k.lastAttempt) < minRefreshInterval {
+		return Key{}, ErrUnknownKey
+	}
+	k.lastAttempt = time.Now()
+	if err := k.Refresh(ctx); err != nil {
+		return Key{}, err
+	}
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+	return Key{}, ErrUnknownKey
+}
+
+func (k *KeySet) lookup(kid string) (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	// a single key without kid matches any token
+	if key, ok := k.keys[kid]; ok {
+		return key, true
+	}
+	if len(k.keys) == 1 && kid == "" {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
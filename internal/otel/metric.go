@@ -0,0 +1,40 @@
+package otel
+
+import (
+	"context"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkMetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+var meter = otel.Meter(instrumentationName)
+var meterProvider *sdkMetric.MeterProvider
+
+func initMeterProvider(ctx context.Context, res *resource.Resource) {
+	cfg := config.Get().OTel
+
+	// export metrics only when otel is enabled
+	opts := []sdkMetric.Option{sdkMetric.WithResource(res)}
+	if cfg.Enabled {
+		exporter, err := otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+			otlpmetricgrpc.WithInsecure())
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create metric exporter")
+		}
+		opts = append(opts, sdkMetric.WithReader(sdkMetric.NewPeriodicReader(exporter)))
+	}
+
+	// create and set meter provider
+	meterProvider = sdkMetric.NewMeterProvider(opts...)
+	otel.SetMeterProvider(meterProvider)
+}
+
+func Meter() metric.Meter {
+	return meter
+}
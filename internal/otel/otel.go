@@ -18,12 +18,11 @@ import (
 
 const instrumentationName = "github.com/ovinc/zerotrust"
 
-var tracer trace.Tracer
+var tracer = otel.Tracer(instrumentationName)
 var tracerProvider *sdkTrace.TracerProvider
 
-func init() {
+func Init() {
 	ctx := context.Background()
-	tracer = otel.Tracer(instrumentationName)
 	cfg := config.Get().OTel
 
 	// build resource with configured attributes
@@ -52,6 +51,9 @@ func init() {
 	otel.SetTracerProvider(tracerProvider)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{}, propagation.Baggage{}))
+
+	// metrics share the same resource and exporter settings
+	initMeterProvider(ctx, res)
 }
 
 func buildResource(ctx context.Context, cfg *config.ResourceConfig) (*resource.Resource, error) {
@@ -70,6 +72,9 @@ func Shutdown(ctx context.Context) {
 	if tracerProvider != nil {
 		_ = tracerProvider.Shutdown(ctx)
 	}
+	if meterProvider != nil {
+		_ = meterProvider.Shutdown(ctx)
+	}
 }
 
 func Tracer() trace.Tracer {
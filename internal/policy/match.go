@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"net"
+	"path"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func Matches(m *config.MatchConfig, host, method, reqPath string) bool {
+	// every configured dimension must match, empty means any
+	if len(m.Hosts) > 0 && !MatchHost(m.Hosts, host) {
+		return false
+	}
+	if len(m.Paths) > 0 && !matchAny(m.Paths, NormalizePath(reqPath)) {
+		return false
+	}
+	if len(m.Methods) > 0 && !containsFold(m.Methods, method) {
+		return false
+	}
+	return true
+}
+
+func MatchPattern(pattern, value string) bool {
+	// trailing wildcard matches any suffix including slashes
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return value == prefix || strings.HasPrefix(value, prefix+"/")
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && !strings.ContainsAny(prefix, "*?[") {
+		return strings.HasPrefix(value, prefix)
+	}
+	// fall back to shell style glob matching
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+func NormalizeHost(host string) string {
+	// strip port and lower case host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+func NormalizePath(reqPath string) string {
+	// drop query string and fragment
+	if i := strings.IndexAny(reqPath, "?#"); i >= 0 {
+		reqPath = reqPath[:i]
+	}
+	if reqPath == "" {
+		return "/"
+	}
+	return reqPath
+}
+
+func MatchHost(patterns []string, host string) bool {
+	// host names are compared case insensitively
+	host = NormalizeHost(host)
+	for _, p := range patterns {
+		if MatchPattern(strings.ToLower(p), host) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if MatchPattern(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/otel"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+const (
+	ReasonAllowed = "policy_allowed"
+	ReasonDenied  = "policy_denied"
+)
+
+var (
+	shadowCounter       metric.Int64Counter
+	disagreementCounter metric.Int64Counter
+)
+
+func init() {
+	var err error
+
+	// count every shadow evaluation and every disagreement with the enforced verdict
+	shadowCounter, err = otel.Meter().Int64Counter(
+		"zerotrust.policy.shadow.evaluations",
+		metric.WithDescription("number of decisions evaluated against shadow rules"),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to create shadow evaluation counter")
+	}
+	disagreementCounter, err = otel.Meter().Int64Counter(
+		"zerotrust.policy.shadow.disagreements",
+		metric.WithDescription("number of decisions where shadow and enforced verdicts differ"),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to create shadow disagreement counter")
+	}
+}
+
+type Input struct {
+	Host    string
+	Method  string
+	Path    string
+	UserID  string
+	Backend string
+	Groups  []string
+	Scopes  []string
+
+	Attributes map[string][]string
+}
+
+type Verdict struct {
+	Allowed bool
+	Rule    string
+	Reason  string
+}
+
+type Decision struct {
+	Verdict
+	Shadow *Verdict
+}
+
+func (d *Decision) Disagrees() bool {
+	return d.Shadow != nil && d.Shadow.Allowed != d.Allowed
+}
+
+func Evaluate(ctx context.Context, in *Input) *Decision {
+	// start span
+	ctx, span := otel.Tracer().Start(ctx, "policy.Evaluate")
+	defer span.End()
+
+	cfg := config.Get().Policy
+
+	// enforced verdict only considers rules that are not in shadow mode
+	decision := &Decision{Verdict: evaluate(cfg, in, false)}
+	if !hasShadowRules(cfg.Rules) {
+		return decision
+	}
+
+	// shadow verdict is what the rule set would decide with shadow rules promoted
+	shadow := evaluate(cfg, in, true)
+	decision.Shadow = &shadow
+
+	// record shadow outcome by configured host pattern and route, raw hosts would let clients create series
+	route := "other"
+	if r := ResolveRoute(in.Host, in.Method, in.Path); r != nil {
+		route = r.Name
+	}
+	attrs := metric.WithAttributes(
+		attribute.String("host", hostLabel(in.Host)),
+		attribute.String("route", route),
+		attribute.String("reason", shadow.Reason),
+		attribute.String("rule", shadow.Rule),
+	)
+	shadowCounter.Add(ctx, 1, attrs)
+	if decision.Disagrees() {
+		disagreementCounter.Add(ctx, 1, attrs)
+	}
+
+	return decision
+}
+
+func hostPatterns(cfg *config.Config) []string {
+	// application profiles group hosts first, then the patterns routes and rules match on
+	var patterns []string
+	for i := range cfg.Applications {
+		patterns = append(patterns, cfg.Applications[i].Hosts...)
+	}
+	for i := range cfg.Routes {
+		patterns = append(patterns, cfg.Routes[i].Match.Hosts...)
+	}
+	for i := range cfg.Policy.Rules {
+		patterns = append(patterns, cfg.Policy.Rules[i].Match.Hosts...)
+	}
+	return patterns
+}
+
+func hostLabel(host string) string {
+	for _, p := range metricHosts {
+		if MatchHost([]string{p}, host) {
+			return p
+		}
+	}
+	return "other"
+}
+
+func evaluate(cfg config.PolicyConfig, in *Input, includeShadow bool) Verdict {
+	// first matching rule wins
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if rule.Shadow && !includeShadow {
+			continue
+		}
+		if !ruleMatches(rule, in) {
+			continue
+		}
+		return newVerdict(rule.Action, rule.Name)
+	}
+
+	// fall back to default action
+	return newVerdict(cfg.DefaultAction, "")
+}
+
+func ruleMatches(rule *config.RuleConfig, in *Input) bool {
+	if !Matches(&rule.Match, in.Host, in.Method, in.Path) {
+		return false
+	}
+	if len(rule.Users) > 0 && !slices.Contains(rule.Users, in.UserID) {
+		return false
+	}
+	if len(rule.Backends) > 0 && !slices.Contains(rule.Backends, in.Backend) {
+		return false
+	}
+	if len(rule.Groups) > 0 && !intersects(rule.Groups, in.Groups) {
+		return false
+	}
+	if len(rule.Scopes) > 0 && !intersects(rule.Scopes, in.Scopes) {
+		return false
+	}
+	return true
+}
+
+func intersects(a, b []string) bool {
+	return slices.ContainsFunc(a, func(v string) bool { return slices.Contains(b, v) })
+}
+
+func validateActions(cfg config.PolicyConfig) error {
+	// a typo must not silently open access, an empty default allows as before
+	if cfg.DefaultAction != "" && !validAction(cfg.DefaultAction) {
+		return fmt.Errorf("default_action %q must be allow or deny", cfg.DefaultAction)
+	}
+	for _, rule := range cfg.Rules {
+		if !validAction(rule.Action) {
+			return fmt.Errorf("rule %q action %q must be allow or deny", rule.Name, rule.Action)
+		}
+	}
+	return nil
+}
+
+func validAction(action string) bool {
+	return action == ActionAllow || action == ActionDeny
+}
+
+func newVerdict(action, rule string) Verdict {
+	// actions are validated at startup
+	if action == ActionDeny {
+		return Verdict{Allowed: false, Rule: rule, Reason: ReasonDenied}
+	}
+	return Verdict{Allowed: true, Rule: rule, Reason: ReasonAllowed}
+}
+
+func hasShadowRules(rules []config.RuleConfig) bool {
+	for _, rule := range rules {
+		if rule.Shadow {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package policy
+
+import "github.com/ovinc/zerotrust/internal/config"
+
+func ResolveRoute(host, method, reqPath string) *config.RouteConfig {
+	// first matching route wins, nil means global settings apply
+	routes := config.Get().Routes
+	for i := range routes {
+		if Matches(&routes[i].Match, host, method, reqPath) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonTenantMismatch = "tenant_mismatch"
+
+type tenantBinding struct {
+	cfg  *config.TenantConfig
+	host *regexp.Regexp
+	path *regexp.Regexp
+}
+
+type ProfileLookup func(ctx context.Context, query, userID string) ([]string, error)
+
+type TenantResult struct {
+	Binding string
+	Tenant  string
+	Allowed bool
+}
+
+var (
+	tenantBindings []*tenantBinding
+	metricHosts    []string
+)
+
+func Init() {
+	// validate rule actions and compile tenant patterns once at startup
+	cfg := config.Get().Policy
+	if err := validateActions(cfg); err != nil {
+		logrus.WithError(err).Fatal("invalid policy")
+	}
+	tenantBindings = make([]*tenantBinding, 0, len(cfg.Tenants))
+	for i := range cfg.Tenants {
+		binding, err := newTenantBinding(&cfg.Tenants[i])
+		if err != nil {
+			logrus.WithError(err).WithField("tenant", cfg.Tenants[i].Name).Fatal("failed to initialize tenant binding")
+		}
+		tenantBindings = append(tenantBindings, binding)
+	}
+	metricHosts = hostPatterns(config.Get())
+}
+
+func newTenantBinding(c *config.TenantConfig) (*tenantBinding, error) {
+	if c.Attribute == "" {
+		return nil, fmt.Errorf("attribute is required")
+	}
+	if c.HostPattern == "" && c.PathPattern == "" {
+		return nil, fmt.Errorf("host_pattern or path_pattern is required")
+	}
+	if c.Query != "" && !config.Get().UserSource.Enabled {
+		return nil, fmt.Errorf("query needs user_source")
+	}
+
+	binding := &tenantBinding{cfg: c}
+	var err error
+	if c.HostPattern != "" {
+		if binding.host, err = regexp.Compile(c.HostPattern); err != nil {
+			return nil, fmt.Errorf("invalid host_pattern: %w", err)
+		}
+	}
+	if c.PathPattern != "" {
+		if binding.path, err = regexp.Compile(c.PathPattern); err != nil {
+			return nil, fmt.Errorf("invalid path_pattern: %w", err)
+		}
+	}
+	return binding, nil
+}
+
+func CheckTenant(ctx context.Context, in *Input, profile ProfileLookup) (*TenantResult, error) {
+	// first binding that applies to the request and yields a tenant key decides
+	for _, binding := range tenantBindings {
+		if !Matches(&binding.cfg.Match, in.Host, in.Method, in.Path) {
+			continue
+		}
+		tenant := binding.extract(in)
+		if tenant == "" {
+			continue
+		}
+
+		// user must carry the tenant key in the configured attribute, else in the profile
+		result := &TenantResult{Binding: binding.cfg.Name, Tenant: tenant}
+		values := in.Attributes[binding.cfg.Attribute]
+		if len(values) == 0 && binding.cfg.Query != "" && profile != nil && in.UserID != "" {
+			var err error
+			if values, err = profile(ctx, binding.cfg.Query, in.UserID); err != nil {
+				return result, err
+			}
+		}
+		for _, value := range values {
+			if strings.EqualFold(value, tenant) {
+				result.Allowed = true
+				break
+			}
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+func (b *tenantBinding) extract(in *Input) string {
+	// host takes precedence over path
+	if b.host != nil {
+		if tenant := submatch(b.host, NormalizeHost(in.Host)); tenant != "" {
+			return tenant
+		}
+	}
+	if b.path != nil {
+		return submatch(b.path, NormalizePath(in.Path))
+	}
+	return ""
+}
+
+func submatch(re *regexp.Regexp, value string) string {
+	match := re.FindStringSubmatch(value)
+	if match == nil {
+		return ""
+	}
+
+	// prefer the named group, then the first group, then the whole match
+	if i := re.SubexpIndex("tenant"); i > 0 {
+		return match[i]
+	}
+	if len(match) > 1 {
+		return match[1]
+	}
+	return match[0]
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+const tenantConfig = `
+user_source:
+  enabled: true
+policy:
+  tenants:
+    - name: saas
+      match:
+        hosts: [ "*.app.example.com" ]
+      host_pattern: "^(?P<tenant>[^.]+)\\.app\\.example\\.com$"
+      attribute: org
+      query: "SELECT slug FROM memberships WHERE user_id = $1"
+`
+
+func TestCheckTenant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(tenantConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.Init(path)
+	Init()
+
+	errProfile := errors.New("database down")
+	profile := func(_ context.Context, query, userID string) ([]string, error) {
+		switch userID {
+		case "1":
+			return []string{"acme"}, nil
+		case "3":
+			return nil, errProfile
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		userID     string
+		attributes map[string][]string
+		profile    ProfileLookup
+		want       *TenantResult
+		wantErr    error
+	}{
+		{name: "other host", host: "www.example.com", userID: "1", profile: profile, want: nil},
+		{name: "attribute match", host: "acme.app.example.com", attributes: map[string][]string{"org": {"ACME"}}, profile: profile,
+			want: &TenantResult{Binding: "saas", Tenant: "acme", Allowed: true}},
+		{name: "attribute mismatch", host: "acme.app.example.com", userID: "1", attributes: map[string][]string{"org": {"globex"}}, profile: profile,
+			want: &TenantResult{Binding: "saas", Tenant: "acme"}},
+		{name: "profile match", host: "acme.app.example.com:443", userID: "1", profile: profile,
+			want: &TenantResult{Binding: "saas", Tenant: "acme", Allowed: true}},
+		{name: "profile mismatch", host: "acme.app.example.com", userID: "2", profile: profile,
+			want: &TenantResult{Binding: "saas", Tenant: "acme"}},
+		{name: "profile error", host: "acme.app.example.com", userID: "3", profile: profile,
+			want: &TenantResult{Binding: "saas", Tenant: "acme"}, wantErr: errProfile},
+		{name: "no profile lookup", host: "acme.app.example.com", userID: "1",
+			want: &TenantResult{Binding: "saas", Tenant: "acme"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &Input{Host: tt.host, Method: "GET", Path: "/", UserID: tt.userID, Attributes: tt.attributes}
+			got, err := CheckTenant(context.Background(), in, tt.profile)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+)
+
+type window struct {
+	current  int64
+	previous int64
+	elapsed  time.Duration
+	size     time.Duration
+}
+
+func (w window) count() float64 {
+	// sliding count, the previous window weighs by its overlap
+	overlap := float64(w.size-w.elapsed) / float64(w.size)
+	return float64(w.previous)*overlap + float64(w.current)
+}
+
+func (w window) remaining() time.Duration {
+	return w.size - w.elapsed
+}
+
+func windowIndex(now time.Time, size time.Duration) (int64, time.Duration) {
+	elapsed := now.UnixNano() % int64(size)
+	return now.UnixNano() / int64(size), time.Duration(elapsed)
+}
+
+type redisCounter struct {
+	prefix string
+}
+
+func (c *redisCounter) key(key string, index int64) string {
+	return c.prefix + key + ":" + strconv.FormatInt(index, 10)
+}
+
+func (c *redisCounter) add(ctx context.Context, key string, size time.Duration, now time.Time) (window, error) {
+	index, elapsed := windowIndex(now, size)
+	s := app.Default().Store
+	current, _, err := s.Incr(ctx, c.key(key, index), 2*size)
+	if err != nil {
+		return window{}, err
+	}
+	counts, err := s.Counts(ctx, c.key(key, index-1))
+	if err != nil {
+		return window{}, err
+	}
+	return window{current: current, previous: counts[0], elapsed: elapsed, size: size}, nil
+}
+
+func (c *redisCounter) get(ctx context.Context, key string, size time.Duration, now time.Time) (window, error) {
+	index, elapsed := windowIndex(now, size)
+	counts, err := app.Default().Store.Counts(ctx, c.key(key, index), c.key(key, index-1))
+	if err != nil {
+		return window{}, err
+	}
+	return window{current: counts[0], previous: counts[1], elapsed: elapsed, size: size}, nil
+}
+
+type memoryCounter struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	index    int64
+	current  int64
+	previous int64
+}
+
+func newMemoryCounter() *memoryCounter {
+	return &memoryCounter{entries: map[string]*memoryEntry{}}
+}
+
+func (c *memoryCounter) entry(key string, index int64) *memoryEntry {
+	e, ok := c.entries[key]
+	if !ok {
+		e = &memoryEntry{index: index}
+		c.entries[key] = e
+	}
+
+	// shift windows that ended since the last request
+	switch {
+	case e.index == index-1:
+		e.previous, e.current = e.current, 0
+	case e.index < index-1:
+		e.previous, e.current = 0, 0
+	}
+	e.index = index
+	return e
+}
+
+func (c *memoryCounter) add(key string, size time.Duration, now time.Time) window {
+	index, elapsed := windowIndex(now, size)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(key, index)
+	e.current++
+	return window{current: e.current, previous: e.previous, elapsed: elapsed, size: size}
+}
+
+func (c *memoryCounter) get(key string, size time.Duration, now time.Time) window {
+	index, elapsed := windowIndex(now, size)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(key, index)
+	return window{current: e.current, previous: e.previous, elapsed: elapsed, size: size}
+}
+
+func (c *memoryCounter) sweep(size time.Duration, now time.Time) {
+	// drop entries whose windows are both over
+	index, _ := windowIndex(now, size)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if e.index < index-1 {
+			delete(c.entries, key)
+		}
+	}
+}
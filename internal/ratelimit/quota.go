@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/store"
+)
+
+const ReasonQuotaExceeded = "quota_exceeded"
+
+const defaultQuotaWindow = time.Hour
+
+var quotasDisabled bool
+
+type Quota struct {
+	Limit     int
+	Remaining int
+	Window    time.Duration
+	Reset     time.Duration
+	Exceeded  bool
+}
+
+func CheckQuota(ctx context.Context, s *store.Store, route *config.RouteConfig, userID string) (*Quota, error) {
+	if quotasDisabled || route == nil || route.Quota == nil || route.Quota.Limit <= 0 || userID == "" {
+		return nil, nil
+	}
+	size := route.Quota.Window
+	if size <= 0 {
+		size = defaultQuotaWindow
+	}
+
+	// fixed windows aligned to the clock, so every instance agrees on the reset
+	index, elapsed := windowIndex(time.Now(), size)
+	key := fmt.Sprintf("%squota:%s:%s:%d", keyPrefix(), route.Name, userID, index)
+	count, _, err := s.Incr(ctx, key, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Quota{
+		Limit:     route.Quota.Limit,
+		Remaining: max(route.Quota.Limit-int(count), 0),
+		Window:    size,
+		Reset:     retryAfter(window{elapsed: elapsed, size: size}),
+		Exceeded:  count > int64(route.Quota.Limit),
+	}, nil
+}
+
+func DisableQuotas() {
+	quotasDisabled = true
+}
+
+func (q *Quota) SetHeaders(header http.Header) {
+	header.Set("RateLimit-Limit", strconv.Itoa(q.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(q.Remaining))
+	header.Set("RateLimit-Reset", Seconds(q.Reset))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", q.Limit, Seconds(q.Window)))
+}
+
+func Seconds(d time.Duration) string {
+	// header values in whole seconds, rounded up so clients never come back too early
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func keyPrefix() string {
+	if prefix := config.Get().RateLimit.KeyPrefix; prefix != "" {
+		return prefix
+	}
+	return defaultKeyPrefix
+}
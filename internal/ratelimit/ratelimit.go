@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/policy"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonLimited = "rate_limited"
+
+const (
+	defaultKeyPrefix = "zerotrust:ratelimit:"
+	defaultWindow    = time.Minute
+	sweepInterval    = time.Minute
+)
+
+type rule struct {
+	name    string
+	limit   int
+	size    time.Duration
+	counter *redisCounter
+	memory  *memoryCounter
+}
+
+type Limiter struct {
+	failures *rule
+}
+
+var (
+	enabled  bool
+	perHost  bool
+	total    *rule
+	failures *rule
+)
+
+func Init() {
+	cfg := config.Get().RateLimit
+	if !cfg.Enabled {
+		return
+	}
+	enabled = true
+	perHost = cfg.PerHost
+
+	total = newRule("total", cfg.Total)
+	failures = newRule("failures", cfg.Failures)
+	go sweep(total, failures)
+}
+
+func NewLimiter(name string, cfg config.RateLimitRule) *Limiter {
+	// counts failures only and works whether or not rate_limit is enabled
+	l := &Limiter{failures: newRule(name, cfg)}
+	if l.failures != nil {
+		go sweep(l.failures)
+	}
+	return l
+}
+
+func (l *Limiter) Allow(ctx context.Context, clientIP string) (time.Duration, bool) {
+	if l.failures == nil {
+		return 0, true
+	}
+	w := l.failures.get(ctx, ipKey(clientIP), time.Now())
+	if w.count() >= float64(l.failures.limit) {
+		return retryAfter(w), false
+	}
+	return 0, true
+}
+
+func (l *Limiter) Fail(ctx context.Context, clientIP string) {
+	if l.failures != nil {
+		l.failures.add(ctx, ipKey(clientIP), time.Now())
+	}
+}
+
+func sweep(rules ...*rule) {
+	// forget fallback counts of idle clients
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for _, r := range rules {
+			if r != nil {
+				r.memory.sweep(r.size, now)
+			}
+		}
+	}
+}
+
+func newRule(name string, cfg config.RateLimitRule) *rule {
+	if cfg.Limit <= 0 {
+		return nil
+	}
+	size := cfg.Window
+	if size <= 0 {
+		size = defaultWindow
+	}
+	return &rule{name: name, limit: cfg.Limit, size: size, counter: &redisCounter{prefix: keyPrefix()}, memory: newMemoryCounter()}
+}
+
+func Allow(ctx context.Context, clientIP, host string) (time.Duration, bool) {
+	if !enabled {
+		return 0, true
+	}
+	key := clientKey(clientIP, host)
+	now := time.Now()
+
+	// the request itself counts towards the total
+	if total != nil {
+		w := total.add(ctx, key, now)
+		if w.count() > float64(total.limit) {
+			return retryAfter(w), false
+		}
+	}
+
+	// failures are only counted by Fail, a client at the limit is turned away
+	if failures != nil {
+		w := failures.get(ctx, key, now)
+		if w.count() >= float64(failures.limit) {
+			return retryAfter(w), false
+		}
+	}
+	return 0, true
+}
+
+func Fail(ctx context.Context, clientIP, host string) {
+	if !enabled || failures == nil {
+		return
+	}
+	failures.add(ctx, clientKey(clientIP, host), time.Now())
+}
+
+func (r *rule) add(ctx context.Context, key string, now time.Time) window {
+	w, err := r.counter.add(ctx, r.name+":"+key, r.size, now)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("failed to count rate limit in redis, counting locally")
+		return r.memory.add(key, r.size, now)
+	}
+	return w
+}
+
+func (r *rule) get(ctx context.Context, key string, now time.Time) window {
+	w, err := r.counter.get(ctx, r.name+":"+key, r.size, now)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("failed to read rate limit from redis, reading local count")
+		return r.memory.get(key, r.size, now)
+	}
+	return w
+}
+
+func clientKey(clientIP, host string) string {
+	if perHost {
+		return ipKey(clientIP) + ":" + policy.NormalizeHost(host)
+	}
+	return ipKey(clientIP)
+}
+
+func ipKey(clientIP string) string {
+	if clientIP == "" {
+		return "unknown"
+	}
+	return clientIP
+}
+
+func retryAfter(w window) time.Duration {
+	return time.Duration(math.Ceil(w.remaining().Seconds())) * time.Second
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func TestWindowCount(t *testing.T) {
+	tests := []struct {
+		name string
+		w    window
+		want float64
+	}{
+		{name: "start of window", w: window{current: 0, previous: 10, elapsed: 0, size: time.Minute}, want: 10},
+		{name: "quarter through", w: window{current: 2, previous: 8, elapsed: 15 * time.Second, size: time.Minute}, want: 8},
+		{name: "half through", w: window{current: 5, previous: 10, elapsed: 30 * time.Second, size: time.Minute}, want: 10},
+		{name: "end of window", w: window{current: 7, previous: 100, elapsed: time.Minute - time.Nanosecond, size: time.Minute}, want: 7},
+		{name: "no previous", w: window{current: 3, elapsed: 45 * time.Second, size: time.Minute}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.w.count(); got < tt.want-1e-6 || got > tt.want+1e-6 {
+				t.Fatalf("count() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{0, time.Minute},
+		{20 * time.Second, 40 * time.Second},
+		{20*time.Second + time.Millisecond, 40 * time.Second},
+		{59*time.Second + 999*time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		w := window{elapsed: tt.elapsed, size: time.Minute}
+		if got := retryAfter(w); got != tt.want {
+			t.Errorf("retryAfter(%v elapsed) = %v, want %v", tt.elapsed, got, tt.want)
+		}
+	}
+	for d, want := range map[time.Duration]string{0: "0", time.Second: "1", 1500 * time.Millisecond: "2", time.Nanosecond: "1", time.Hour: "3600"} {
+		if got := Seconds(d); got != want {
+			t.Errorf("Seconds(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestWindowIndex(t *testing.T) {
+	start := time.Unix(1_700_000_040, 0)
+	index, elapsed := windowIndex(start, time.Minute)
+	if elapsed != 0 {
+		t.Fatalf("elapsed = %v at a window start", elapsed)
+	}
+	if next, elapsed := windowIndex(start.Add(59*time.Second), time.Minute); next != index || elapsed != 59*time.Second {
+		t.Fatalf("windowIndex(+59s) = %d, %v", next, elapsed)
+	}
+	if next, _ := windowIndex(start.Add(time.Minute), time.Minute); next != index+1 {
+		t.Fatalf("windowIndex(+1m) = %d, want %d", next, index+1)
+	}
+}
+
+func TestMemoryCounter(t *testing.T) {
+	c := newMemoryCounter()
+	size := time.Minute
+	start := time.Unix(1_700_000_040, 0)
+
+	// five requests in the first window
+	var w window
+	for range 5 {
+		w = c.add("a", size, start.Add(10*time.Second))
+	}
+	if w.current != 5 || w.previous != 0 {
+		t.Fatalf("first window = %+v", w)
+	}
+
+	// the next window starts empty and carries the previous one
+	w = c.get("a", size, start.Add(size+15*time.Second))
+	if w.current != 0 || w.previous != 5 || w.count() != 3.75 {
+		t.Fatalf("next window = %+v, count %v", w, w.count())
+	}
+	w = c.add("a", size, start.Add(size+30*time.Second))
+	if w.current != 1 || w.previous != 5 || w.count() != 3.5 {
+		t.Fatalf("next window after add = %+v, count %v", w, w.count())
+	}
+
+	// a skipped window forgets everything, other keys are independent
+	if w = c.get("a", size, start.Add(3*size)); w.current != 0 || w.previous != 0 {
+		t.Fatalf("after idle window = %+v", w)
+	}
+	if w = c.add("b", size, start.Add(3*size)); w.current != 1 {
+		t.Fatalf("other key = %+v", w)
+	}
+
+	// sweeping drops entries whose windows are both over
+	c.sweep(size, start.Add(4*size))
+	if len(c.entries) != 2 {
+		t.Fatalf("entries after sweep = %d, want 2", len(c.entries))
+	}
+	c.sweep(size, start.Add(5*size))
+	if len(c.entries) != 0 {
+		t.Fatalf("entries after sweep = %d, want 0", len(c.entries))
+	}
+}
+
+func TestRedisCounter(t *testing.T) {
+	server := miniredis.RunT(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := fmt.Sprintf("redis:\n  host: %q\n  port: %s\n", server.Host(), server.Port())
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.Init(path)
+	app.Init()
+	t.Cleanup(app.Close)
+
+	ctx := context.Background()
+	c := &redisCounter{prefix: "test:"}
+	size := time.Minute
+	start := time.Unix(1_700_000_040, 0)
+	for range 4 {
+		if _, err := c.add(ctx, "a", size, start.Add(10*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := c.add(ctx, "a", size, start.Add(size+45*time.Second))
+	if err != nil || w.current != 1 || w.previous != 4 || w.count() != 2 {
+		t.Fatalf("add() = %+v, count %v, %v", w, w.count(), err)
+	}
+	if w, err = c.get(ctx, "a", size, start.Add(size+45*time.Second)); err != nil || w.current != 1 || w.previous != 4 {
+		t.Fatalf("get() = %+v, %v", w, err)
+	}
+
+	// keys outlive their window so the next one can weigh them
+	index, _ := windowIndex(start, size)
+	if ttl := server.TTL(c.key("a", index)); ttl != 2*size {
+		t.Fatalf("ttl = %v, want %v", ttl, 2*size)
+	}
+
+	// the admin style limiter turns a client away at its failure limit
+	l := NewLimiter("test", config.RateLimitRule{Limit: 2, Window: time.Minute})
+	for i := range 3 {
+		retryAfter, ok := l.Allow(ctx, "203.0.113.7")
+		if ok != (i < 2) || !ok && (retryAfter <= 0 || retryAfter > time.Minute) {
+			t.Fatalf("attempt %d: Allow() = %v, %v", i, retryAfter, ok)
+		}
+		l.Fail(ctx, "203.0.113.7")
+	}
+	if _, ok := l.Allow(ctx, "203.0.113.8"); !ok {
+		t.Fatal("other client is limited")
+	}
+}
@@ -0,0 +1,44 @@
+package replay
+
+import (
+	"strconv"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+)
+
+func StartMemory() (*miniredis.Miniredis, error) {
+	// start in memory redis server
+	server, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	// rewrite redis config to use the in memory server, databases are kept
+	cfg := config.Get()
+	port, _ := strconv.Atoi(server.Port())
+	redirect := func(r *config.RedisConfig) {
+		r.Host = server.Host()
+		r.Port = port
+		r.Password = ""
+	}
+	redirect(&cfg.Redis)
+	for i := range cfg.Applications {
+		if cfg.Applications[i].Redis.Host != "" {
+			redirect(&cfg.Applications[i].Redis)
+		}
+	}
+
+	app.Init()
+	return server, nil
+}
+
+func SeedSession(server *miniredis.Miniredis, a *app.Application, sessionID string, values map[string]interface{}) error {
+	// encode with the application decoder format and store under its key format
+	data, err := a.Decoder.Encode(values)
+	if err != nil {
+		return err
+	}
+	return server.DB(a.Redis.DB).Set(a.Redis.FormatSessionKey(sessionID), string(data))
+}
@@ -0,0 +1,149 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/handler"
+)
+
+type Record struct {
+	Line      int    `json:"-"`
+	Message   string `json:"message"`
+	ClientIP  string `json:"client_ip"`
+	Method    string `json:"method"`
+	Protocol  string `json:"protocol"`
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	RequestID string `json:"request_id"`
+	UserAgent string `json:"user_agent"`
+	Referer   string `json:"referer"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Reason    string `json:"reason"`
+}
+
+type Change struct {
+	Record *Record
+	Before string
+	After  string
+	Rule   string
+}
+
+type Report struct {
+	Replayed int
+	Ignored  int
+	Changes  []Change
+}
+
+func Run(ctx context.Context, server *miniredis.Miniredis, r io.Reader) (*Report, error) {
+	report := &Report{}
+
+	// decision log lines can be long because of user agents and referers
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		// only decision entries are replayed, anything else is ignored
+		record := &Record{Line: line}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil || !isDecision(record) {
+			report.Ignored++
+			continue
+		}
+
+		// rebuild the stubbed session and replay the request
+		req, err := prepare(server, record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		decision := handler.Authorize(ctx, req)
+		report.Replayed++
+
+		// compare outcome with the recorded one
+		before := describe(record.Message, record.Reason)
+		after := describe(decision.Result, decision.Reason)
+		if before != after {
+			change := Change{Record: record, Before: before, After: after}
+			if decision.Policy != nil {
+				change.Rule = decision.Policy.Rule
+			}
+			report.Changes = append(report.Changes, change)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func RunFile(ctx context.Context, server *miniredis.Miniredis, path string) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Run(ctx, server, f)
+}
+
+func isDecision(record *Record) bool {
+	return strings.HasPrefix(record.Message, "request ") && record.Host != "" && record.Method != ""
+}
+
+func prepare(server *miniredis.Miniredis, record *Record) (*handler.VerifyRequest, error) {
+	req := &handler.VerifyRequest{
+		ClientIP:  record.ClientIP,
+		Method:    record.Method,
+		Protocol:  record.Protocol,
+		Host:      record.Host,
+		Path:      record.Path,
+		UserAgent: record.UserAgent,
+		Referer:   record.Referer,
+		RequestID: record.RequestID,
+	}
+
+	// logged session ids are masked, so derive a stable stub id per session and user
+	application := app.Resolve(record.Host)
+	sessionID := fmt.Sprintf("replay:%s:%s", record.SessionID, record.UserID)
+	sessionKey := application.Redis.FormatSessionKey(sessionID)
+	db := server.DB(application.Redis.DB)
+
+	// recreate the session state the recorded decision observed
+	switch {
+	case record.Reason == "missing_session":
+		return req, nil
+	case record.Reason == "session_store_error":
+		db.Del(sessionKey)
+	case record.Reason == "session_parse_error":
+		if err := db.Set(sessionKey, "invalid"); err != nil {
+			return nil, err
+		}
+	case record.UserID != "":
+		if err := SeedSession(server, application, sessionID, map[string]interface{}{"_auth_user_id": record.UserID}); err != nil {
+			return nil, err
+		}
+	default:
+		// session state is unknown, e.g. skipped methods, assume none was sent
+		return req, nil
+	}
+
+	req.SessionID = sessionID
+	return req, nil
+}
+
+func describe(result, reason string) string {
+	if reason == "" {
+		return result
+	}
+	return fmt.Sprintf("%s (%s)", result, reason)
+}
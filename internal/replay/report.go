@@ -0,0 +1,48 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+func (r *Report) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "replayed %d decisions, ignored %d other lines\n", r.Replayed, r.Ignored)
+	_, _ = fmt.Fprintf(w, "%d decisions changed\n", len(r.Changes))
+	if len(r.Changes) == 0 {
+		return
+	}
+
+	// summarize transitions by count
+	counts := map[[2]string]int{}
+	for _, c := range r.Changes {
+		counts[[2]string{c.Before, c.After}]++
+	}
+	transitions := make([][2]string, 0, len(counts))
+	for t := range counts {
+		transitions = append(transitions, t)
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		if counts[transitions[i]] != counts[transitions[j]] {
+			return counts[transitions[i]] > counts[transitions[j]]
+		}
+		return transitions[i][0]+transitions[i][1] < transitions[j][0]+transitions[j][1]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\nCOUNT\tBEFORE\tAFTER")
+	for _, t := range transitions {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", counts[t], t[0], t[1])
+	}
+	_ = tw.Flush()
+
+	// list every changed decision
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\nLINE\tMETHOD\tHOST\tPATH\tUSER\tBEFORE\tAFTER\tRULE")
+	for _, c := range r.Changes {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Record.Line, c.Record.Method, c.Record.Host, c.Record.Path, c.Record.UserID, c.Before, c.After, c.Rule)
+	}
+	_ = tw.Flush()
+}
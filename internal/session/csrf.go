@@ -0,0 +1,51 @@
+package session
+
+import (
+	"crypto/subtle"
+	"strings"
+)
+
+const (
+	csrfAllowedChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	csrfSecretLength = 32
+	csrfMaskedLength = 2 * csrfSecretLength
+)
+
+func CheckCSRF(cookie, token string) bool {
+	// either side may be a plain secret or a masked token, as in django.middleware.csrf
+	secret, ok := csrfSecret(cookie)
+	if !ok {
+		return false
+	}
+	presented, ok := csrfSecret(token)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(presented)) == 1
+}
+
+func csrfSecret(token string) (string, bool) {
+	for _, c := range token {
+		if !strings.ContainsRune(csrfAllowedChars, c) {
+			return "", false
+		}
+	}
+
+	switch len(token) {
+	case csrfSecretLength:
+		return token, true
+	case csrfMaskedLength:
+		// unmask, each cipher char is shifted by the matching mask char
+		mask, cipher := token[:csrfSecretLength], token[csrfSecretLength:]
+		n := len(csrfAllowedChars)
+		secret := make([]byte, csrfSecretLength)
+		for i := range secret {
+			x := strings.IndexByte(csrfAllowedChars, cipher[i])
+			y := strings.IndexByte(csrfAllowedChars, mask[i])
+			secret[i] = csrfAllowedChars[(x-y+n)%n]
+		}
+		return string(secret), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func maskCSRF(secret, mask string) string {
+	// django.middleware.csrf._mask_cipher_secret
+	n := len(csrfAllowedChars)
+	cipher := make([]byte, len(secret))
+	for i := range cipher {
+		x := strings.IndexByte(csrfAllowedChars, secret[i])
+		y := strings.IndexByte(csrfAllowedChars, mask[i])
+		cipher[i] = csrfAllowedChars[(x+y)%n]
+	}
+	return mask + string(cipher)
+}
+
+func TestCheckCSRF(t *testing.T) {
+	secret := "Zq8xv2LmT4rN0cYb7WkD1sEhJ6uFgP3a"
+	other := "aP3gFu6JhEs1DkW7bYc0Nr4TmL2vx8qZ"
+	mask1 := strings.Repeat("z9", 16)
+	mask2 := "abcdefghijklmnopqrstuvwxyzABCDEF"
+
+	tests := []struct {
+		name   string
+		cookie string
+		token  string
+		want   bool
+	}{
+		{name: "plain secrets", cookie: secret, token: secret, want: true},
+		{name: "masked token", cookie: secret, token: maskCSRF(secret, mask1), want: true},
+		{name: "masked cookie", cookie: maskCSRF(secret, mask1), token: secret, want: true},
+		{name: "different masks", cookie: maskCSRF(secret, mask1), token: maskCSRF(secret, mask2), want: true},
+		{name: "zero mask", cookie: secret, token: maskCSRF(secret, strings.Repeat("a", 32)), want: true},
+		{name: "other secret", cookie: secret, token: other, want: false},
+		{name: "other masked secret", cookie: secret, token: maskCSRF(other, mask2), want: false},
+		{name: "masked token compared as is", cookie: maskCSRF(secret, mask1), token: maskCSRF(secret, mask1)[:32], want: false},
+		{name: "invalid character", cookie: secret, token: secret[:31] + "-", want: false},
+		{name: "wrong length", cookie: secret, token: secret[:31], want: false},
+		{name: "too long", cookie: secret, token: secret + secret + "a", want: false},
+		{name: "empty token", cookie: secret, token: "", want: false},
+		{name: "empty cookie", cookie: "", token: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckCSRF(tt.cookie, tt.token); got != tt.want {
+				t.Fatalf("CheckCSRF(%q, %q) = %v, want %v", tt.cookie, tt.token, got, tt.want)
+			}
+		})
+	}
+}
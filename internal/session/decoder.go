@@ -0,0 +1,43 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	FormatPickle = "pickle"
+	FormatJSON   = "json"
+)
+
+type Decoder struct {
+	Format     string
+	Attributes map[string]string
+}
+
+func NewDecoder(format string, attributes map[string]string) (*Decoder, error) {
+	// pickle is what django-redis uses by default
+	switch format {
+	case "":
+		format = FormatPickle
+	case FormatPickle, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown session decoder %q", format)
+	}
+	return &Decoder{Format: format, Attributes: attributes}, nil
+}
+
+func (d *Decoder) Decode(ctx context.Context, data []byte) (*UserInfo, error) {
+	if d.Format == FormatJSON {
+		return ParseJSONSession(ctx, data, d.Attributes)
+	}
+	return ParseDjangoSession(ctx, data, d.Attributes)
+}
+
+func (d *Decoder) Encode(values map[string]interface{}) ([]byte, error) {
+	if d.Format == FormatJSON {
+		return json.Marshal(values)
+	}
+	return EncodeDjangoSession(values)
+}
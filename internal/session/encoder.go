@@ -0,0 +1,106 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	opProto      = 0x80
+	opEmptyDict  = '}'
+	opEmptyList  = ']'
+	opMark       = '('
+	opSetItems   = 'u'
+	opAppends    = 'e'
+	opBinUnicode = 'X'
+	opBinInt     = 'J'
+	opBinFloat   = 'G'
+	opNone       = 'N'
+	opNewTrue    = 0x88
+	opNewFalse   = 0x89
+	opStop       = '.'
+)
+
+func EncodeDjangoSession(values map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{opProto, 2, opEmptyDict})
+
+	// sort keys so that output is stable
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// write all items in a single setitems batch
+	if len(keys) > 0 {
+		buf.WriteByte(opMark)
+		for _, key := range keys {
+			writeString(&buf, key)
+			if err := writeValue(&buf, values[key]); err != nil {
+				return nil, fmt.Errorf("encode session key %q: %w", key, err)
+			}
+		}
+		buf.WriteByte(opSetItems)
+	}
+
+	buf.WriteByte(opStop)
+	return buf.Bytes(), nil
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) error {
+	// handle the value types a django session usually holds
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(opNone)
+	case string:
+		writeString(buf, val)
+	case bool:
+		if val {
+			buf.WriteByte(opNewTrue)
+		} else {
+			buf.WriteByte(opNewFalse)
+		}
+	case int:
+		if val < math.MinInt32 || val > math.MaxInt32 {
+			return fmt.Errorf("integer %d out of range", val)
+		}
+		buf.WriteByte(opBinInt)
+		_ = binary.Write(buf, binary.LittleEndian, int32(val))
+	case int64:
+		return writeValue(buf, int(val))
+	case float64:
+		buf.WriteByte(opBinFloat)
+		_ = binary.Write(buf, binary.BigEndian, val)
+	case []string:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = item
+		}
+		return writeValue(buf, items)
+	case []interface{}:
+		buf.WriteByte(opEmptyList)
+		if len(val) == 0 {
+			return nil
+		}
+		buf.WriteByte(opMark)
+		for _, item := range val {
+			if err := writeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(opAppends)
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(opBinUnicode)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+	buf.WriteString(s)
+}
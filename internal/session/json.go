@@ -0,0 +1,25 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ovinc/zerotrust/internal/otel"
+)
+
+func ParseJSONSession(ctx context.Context, data []byte, attributes map[string]string) (*UserInfo, error) {
+	// start span
+	_, span := otel.Tracer().Start(ctx, "session.ParseJSONSession")
+	defer span.End()
+
+	// session data should be a json object
+	var sessionDict map[string]interface{}
+	if err := json.Unmarshal(data, &sessionDict); err != nil || sessionDict == nil {
+		return nil, ErrInvalidSession
+	}
+
+	return buildUserInfo(func(key interface{}) (interface{}, bool) {
+		value, ok := sessionDict[key.(string)]
+		return value, ok
+	}, attributes)
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"math/big"
+	"sort"
+	"strconv"
 
 	"github.com/nlpodyssey/gopickle/pickle"
 	"github.com/nlpodyssey/gopickle/types"
@@ -15,13 +18,19 @@ var (
 	ErrUserNotFound   = errors.New("user not found in session")
 )
 
+const (
+	AttributeGroups = "groups"
+	AttributeScopes = "scopes"
+)
+
 type UserInfo struct {
-	UserID   string
-	Backend  string
-	UserHash string
+	UserID     string
+	Backend    string
+	UserHash   string
+	Attributes map[string][]string
 }
 
-func ParseDjangoSession(ctx context.Context, data []byte) (*UserInfo, error) {
+func ParseDjangoSession(ctx context.Context, data []byte, attributes map[string]string) (*UserInfo, error) {
 	// start span
 	_, span := otel.Tracer().Start(ctx, "session.ParseDjangoSession")
 	defer span.End()
@@ -42,25 +51,39 @@ func ParseDjangoSession(ctx context.Context, data []byte) (*UserInfo, error) {
 		return nil, ErrInvalidSession
 	}
 
+	return buildUserInfo(sessionDict.Get, attributes)
+}
+
+func buildUserInfo(get func(key interface{}) (interface{}, bool), attributes map[string]string) (*UserInfo, error) {
 	userInfo := &UserInfo{}
 
 	// extract user id from session (required field)
-	if userID, ok := sessionDict.Get("_auth_user_id"); ok {
+	if userID, ok := get("_auth_user_id"); ok {
 		userInfo.UserID = toString(userID)
 	} else {
 		return nil, ErrUserNotFound
 	}
 
 	// extract auth backend from session (optional field)
-	if backend, ok := sessionDict.Get("_auth_user_backend"); ok {
+	if backend, ok := get("_auth_user_backend"); ok {
 		userInfo.Backend = toString(backend)
 	}
 
 	// extract user hash from session (optional field)
-	if userHash, ok := sessionDict.Get("_auth_user_hash"); ok {
+	if userHash, ok := get("_auth_user_hash"); ok {
 		userInfo.UserHash = toString(userHash)
 	}
 
+	// extract configured attributes from session (optional fields)
+	for name, key := range attributes {
+		if value, ok := get(key); ok {
+			if userInfo.Attributes == nil {
+				userInfo.Attributes = map[string][]string{}
+			}
+			userInfo.Attributes[name] = toStrings(value)
+		}
+	}
+
 	return userInfo, nil
 }
 
@@ -71,7 +94,51 @@ func toString(v interface{}) string {
 		return val
 	case []byte:
 		return string(val)
+	case int:
+		return strconv.Itoa(val)
+	case *big.Int:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
 	default:
 		return ""
 	}
 }
+
+func toStrings(v interface{}) []string {
+	// flatten python sequences into string slices
+	var items []interface{}
+	switch val := v.(type) {
+	case *types.List:
+		items = *val
+	case *types.Tuple:
+		items = *val
+	case []interface{}:
+		items = val
+	case *types.Set:
+		values := make([]string, 0, len(*val))
+		for item := range *val {
+			values = append(values, toString(item))
+		}
+		sort.Strings(values)
+		return values
+	default:
+		return []string{toString(v)}
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, toString(item))
+	}
+	return values
+}
+
+func (u *UserInfo) Attribute(name string) string {
+	// first value of a configured attribute
+	if values := u.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package sessionlimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const ReasonExceeded = "session_limit_exceeded"
+
+const (
+	ActionDenyNew      = "deny_new"
+	ActionRevokeOldest = "revoke_oldest"
+)
+
+const (
+	defaultKeyFormat = "zerotrust:user_sessions:{user_id}"
+	defaultTTL       = 14 * 24 * time.Hour
+)
+
+type Session struct {
+	SessionID string    `json:"session_id"`
+	LastUsed  time.Time `json:"last_used_at"`
+}
+
+type Result struct {
+	Denied  bool
+	Revoked []string
+}
+
+var cfg config.SessionLimitConfig
+
+func Init() {
+	cfg = config.Get().SessionLimit
+	if !cfg.Enabled {
+		return
+	}
+
+	// fill defaults
+	if cfg.KeyFormat == "" {
+		cfg.KeyFormat = defaultKeyFormat
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.Action == "" {
+		cfg.Action = ActionDenyNew
+	}
+
+	// validate
+	if cfg.MaxSessions <= 0 {
+		logrus.Fatal("session_limit.max_sessions must be positive")
+	}
+	if cfg.Action != ActionDenyNew && cfg.Action != ActionRevokeOldest {
+		logrus.WithField("action", cfg.Action).Fatal("session_limit.action must be deny_new or revoke_oldest")
+	}
+}
+
+func Enabled() bool {
+	return cfg.Enabled
+}
+
+func Track(ctx context.Context, a *app.Application, userID, sessionID string) (*Result, error) {
+	key := key(userID)
+	added, err := a.Store.TouchMember(ctx, key, sessionID, time.Now(), cfg.TTL)
+	if err != nil || !added {
+		return nil, err
+	}
+
+	// known sessions pass, a new one is checked against the others still alive
+	active, err := Active(ctx, a, userID)
+	if err != nil {
+		return nil, err
+	}
+	excess := len(active) - cfg.MaxSessions
+	if excess <= 0 {
+		return nil, nil
+	}
+	if cfg.Action == ActionDenyNew {
+		return &Result{Denied: true}, a.Store.RemoveMembers(ctx, key, sessionID)
+	}
+
+	// least recently used sessions go first, never the new one
+	result := &Result{}
+	for _, session := range active {
+		if len(result.Revoked) == excess {
+			break
+		}
+		if session.SessionID == sessionID {
+			continue
+		}
+		if err := a.RevokeSession(ctx, session.SessionID); err != nil {
+			return result, err
+		}
+		result.Revoked = append(result.Revoked, session.SessionID)
+	}
+	return result, a.Store.RemoveMembers(ctx, key, result.Revoked...)
+}
+
+func Active(ctx context.Context, a *app.Application, userID string) ([]Session, error) {
+	key := key(userID)
+	members, err := a.Store.Members(ctx, key)
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+	sessionIDs := make([]string, len(members))
+	for i, z := range members {
+		sessionIDs[i], _ = z.Member.(string)
+	}
+	exist, err := a.Store.SessionsExist(ctx, sessionIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]Session, 0, len(members))
+	var gone []string
+	for i, z := range members {
+		if !exist[i] {
+			gone = append(gone, sessionIDs[i])
+			continue
+		}
+		active = append(active, Session{SessionID: sessionIDs[i], LastUsed: time.UnixMilli(int64(z.Score)).UTC()})
+	}
+	if len(gone) > 0 {
+		if err := a.Store.RemoveMembers(ctx, key, gone...); err != nil {
+			return nil, err
+		}
+	}
+	return active, nil
+}
+
+func key(userID string) string {
+	return strings.ReplaceAll(cfg.KeyFormat, "{user_id}", userID)
+}
@@ -0,0 +1,97 @@
+package sessiontouch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/binding"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultKeyFormat   = "zerotrust:session_touch:{session}"
+	defaultInterval    = 5 * time.Minute
+	defaultMaxLifetime = 30 * 24 * time.Hour
+)
+
+var cfg config.SessionTouchConfig
+
+func Init() {
+	cfg = config.Get().SessionTouch
+	if !cfg.Enabled {
+		return
+	}
+
+	// fill defaults
+	if cfg.KeyFormat == "" {
+		cfg.KeyFormat = defaultKeyFormat
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.MaxLifetime <= 0 {
+		cfg.MaxLifetime = defaultMaxLifetime
+	}
+
+	// validate
+	if cfg.TTL <= 0 {
+		logrus.Fatal("session_touch.ttl must be set to the django session age")
+	}
+}
+
+func Enabled() bool {
+	return cfg.Enabled
+}
+
+func Touch(ctx context.Context, s *store.Store, sessionID string) (bool, error) {
+	prefix := strings.ReplaceAll(cfg.KeyFormat, "{session}", hash(sessionID))
+	now := time.Now()
+
+	// throttle per session across instances
+	due, err := s.SetNX(ctx, prefix+":throttle", "1", cfg.Interval)
+	if err != nil || !due {
+		return false, err
+	}
+
+	// the lifetime counts from the first time the session was seen
+	firstSeen := now
+	created, err := s.SetNX(ctx, prefix+":first_seen", strconv.FormatInt(now.Unix(), 10), cfg.MaxLifetime)
+	if err != nil {
+		return false, err
+	}
+	if !created {
+		value, err := s.Get(ctx, prefix+":first_seen")
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+		if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+			firstSeen = time.Unix(unix, 0)
+		}
+	}
+
+	// extend up to the ttl, less when the max lifetime ends sooner
+	ttl := min(cfg.TTL, firstSeen.Add(cfg.MaxLifetime).Sub(now))
+	if ttl <= 0 {
+		return false, nil
+	}
+	extended, err := s.ExtendSession(ctx, sessionID, ttl)
+	if err != nil || !extended || !binding.Enabled() {
+		return extended, err
+	}
+
+	// the binding has to outlive the session it protects
+	return true, binding.Extend(ctx, s, sessionID, ttl)
+}
+
+func hash(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,127 @@
+package stepup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ovinc/zerotrust/internal/auth"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/usersource"
+)
+
+const ReasonReauthRequired = "reauth_required"
+
+const (
+	SourceAttribute  = "attribute"
+	SourceUserSource = "user_source"
+)
+
+const (
+	defaultAttribute = "auth_time"
+	defaultParam     = "reauth"
+)
+
+var layouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07",
+	"2006-01-02 15:04:05",
+}
+
+type Result struct {
+	AuthTime time.Time
+	Source   string
+	Stale    bool
+}
+
+var cfg config.StepUpConfig
+
+func Init() {
+	cfg = config.Get().StepUp
+
+	// fill defaults
+	if cfg.Attribute == "" {
+		cfg.Attribute = defaultAttribute
+	}
+	if cfg.Param == "" {
+		cfg.Param = defaultParam
+	}
+}
+
+func Param() string {
+	return cfg.Param
+}
+
+func Check(ctx context.Context, route *config.RouteConfig, identity *auth.Identity) (*Result, error) {
+	if route == nil || route.MaxAuthAge <= 0 {
+		return nil, nil
+	}
+
+	// machine credentials only pass where the route exempts their authenticator
+	if slices.Contains(route.StepUpExempt, identity.Authenticator) {
+		return nil, nil
+	}
+
+	// unknown login times count as stale
+	authTime, source, err := AuthTime(ctx, identity, route.MaxAuthAge)
+	result := &Result{AuthTime: authTime, Source: source, Stale: true}
+	if err != nil || authTime.IsZero() {
+		return result, err
+	}
+	result.Stale = time.Since(authTime) > route.MaxAuthAge
+	return result, nil
+}
+
+func AuthTime(ctx context.Context, identity *auth.Identity, maxAge time.Duration) (time.Time, string, error) {
+	// the login time comes with the identity, else from last_login of the user
+	if values := identity.Attributes[cfg.Attribute]; len(values) > 0 {
+		if values[0] == "" {
+			return time.Time{}, SourceAttribute, nil
+		}
+		authTime, err := parseTime(values[0])
+		return authTime, SourceAttribute, err
+	}
+	if !usersource.Enabled() || identity.UserID == "" {
+		return time.Time{}, "", nil
+	}
+	authTime, cached, err := lastLogin(ctx, identity.UserID, true)
+
+	// a cached login time past the allowed age may predate a new login, so ask the database again
+	if err == nil && cached && time.Since(authTime) > maxAge {
+		authTime, _, err = lastLogin(ctx, identity.UserID, false)
+	}
+	return authTime, SourceUserSource, err
+}
+
+func lastLogin(ctx context.Context, userID string, useCache bool) (time.Time, bool, error) {
+	value, cached, err := usersource.LastLogin(ctx, userID, useCache)
+	if err != nil || value == "" {
+		return time.Time{}, cached, err
+	}
+	authTime, err := parseTime(value)
+	return authTime, cached, err
+}
+
+func parseTime(value string) (time.Time, error) {
+	// unix seconds, as stored by time.time() or selected by the default query
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return time.Time{}, fmt.Errorf("invalid login time %q", value)
+		}
+		// microseconds are all python and postgres keep, the rest is float noise
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3), nil
+	}
+	// iso 8601 or sql timestamps, naive times are utc as with django USE_TZ
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid login time %q", value)
+}
@@ -0,0 +1,46 @@
+package stepup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 6, 10, 6, 13, 20, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+		fails bool
+	}{
+		{value: "1718000000", want: want},
+		{value: "1718000000.5", want: want.Add(500 * time.Millisecond)},
+		{value: "1718000000.123456", want: want.Add(123456 * time.Microsecond)},
+		{value: "2024-06-10T06:13:20Z", want: want},
+		{value: "2024-06-10T08:13:20+02:00", want: want},
+		{value: "2024-06-10T06:13:20.123456+00:00", want: want.Add(123456 * time.Microsecond)},
+		{value: "2024-06-10T06:13:20", want: want},
+		{value: "2024-06-10 06:13:20+00:00", want: want},
+		{value: "2024-06-10 08:13:20+02", want: want},
+		{value: "2024-06-10 06:13:20.5", want: want.Add(500 * time.Millisecond)},
+		{value: "2024-06-10 06:13:20", want: want},
+		{value: "", fails: true},
+		{value: "yesterday", fails: true},
+		{value: "2024-06-10", fails: true},
+		{value: "NaN", fails: true},
+		{value: "Inf", fails: true},
+		{value: "-Inf", fails: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.value)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("parseTime(%q) = %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -3,26 +3,33 @@ package store
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ovinc/zerotrust/internal/config"
 	"github.com/ovinc/zerotrust/internal/otel"
 	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
-	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 )
 
-var client *redis.Client
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
 
-func init() {
-	// init
-	ctx := context.Background()
-
-	// load config
-	cfg := config.Get().Redis
+type Store struct {
+	client *redis.Client
+	cfg    config.RedisConfig
+}
 
+func New(ctx context.Context, cfg *config.RedisConfig) (*Store, error) {
 	// create redis client with config
-	client = redis.NewClient(&redis.Options{
+	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
 		Password: cfg.Password,
 		DB:       cfg.DB,
@@ -37,30 +44,248 @@ func init() {
 			attribute.String("db.system", "Redis"),
 		),
 	); err != nil {
-		logrus.WithContext(ctx).WithError(err).Fatal("failed to instrument redis tracing")
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to instrument redis tracing: %w", err)
 	}
 
 	// ping redis to verify connectivity
 	if err := client.Ping(ctx).Err(); err != nil {
-		logrus.WithContext(ctx).WithError(err).Fatal("failed to ping redis")
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
 	}
+
+	return &Store{client: client, cfg: *cfg}, nil
 }
 
-func GetSession(ctx context.Context, sessionID string) (string, error) {
+func (s *Store) GetSession(ctx context.Context, sessionID string) (string, error) {
 	// start new span
 	ctx, span := otel.Tracer().Start(ctx, "store.redis.GetSession")
 	defer span.End()
 
 	// get session data from redis
-	return client.Get(ctx, config.Get().Redis.FormatSessionKey(sessionID)).Result()
+	return s.client.Get(ctx, s.cfg.FormatSessionKey(sessionID)).Result()
 }
 
-func Ping(ctx context.Context) error {
-	return client.Ping(ctx).Err()
+func (s *Store) DeleteSession(ctx context.Context, sessionID string) error {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.DeleteSession")
+	defer span.End()
+
+	// delete session data from redis
+	return s.client.Del(ctx, s.cfg.FormatSessionKey(sessionID)).Err()
 }
 
-func Close() {
-	if client != nil {
-		_ = client.Close()
+func (s *Store) ScanSessions(ctx context.Context, fn func(sessionID string) error) error {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.ScanSessions")
+	defer span.End()
+
+	// match every key of the session key format
+	prefix, suffix, _ := strings.Cut(s.cfg.SessionKeyFormat, "{session_id}")
+	iter := s.client.Scan(ctx, 0, globEscape(prefix)+"*"+globEscape(suffix), 1000).Iterator()
+	for iter.Next(ctx) {
+		sessionID := strings.TrimSuffix(strings.TrimPrefix(iter.Val(), prefix), suffix)
+		if err := fn(sessionID); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func globEscape(s string) string {
+	return globReplacer.Replace(s)
+}
+
+var globReplacer = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+func (s *Store) SessionTTL(ctx context.Context, sessionID string) (time.Duration, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.SessionTTL")
+	defer span.End()
+
+	// remaining lifetime of the session key, negative when it never expires
+	return s.client.TTL(ctx, s.cfg.FormatSessionKey(sessionID)).Result()
+}
+
+func (s *Store) SessionsExist(ctx context.Context, sessionIDs ...string) ([]bool, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.SessionsExist")
+	defer span.End()
+
+	// check every session key in one round trip
+	cmds := make([]*redis.IntCmd, len(sessionIDs))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, sessionID := range sessionIDs {
+			cmds[i] = pipe.Exists(ctx, s.cfg.FormatSessionKey(sessionID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	exist := make([]bool, len(cmds))
+	for i, cmd := range cmds {
+		exist[i] = cmd.Val() > 0
+	}
+	return exist, nil
+}
+
+func (s *Store) ExtendSession(ctx context.Context, sessionID string, ttl time.Duration) (bool, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.ExtendSession")
+	defer span.End()
+
+	return s.ExtendKey(ctx, s.cfg.FormatSessionKey(sessionID), ttl)
+}
+
+func (s *Store) ExtendKey(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	// only ever lengthen the expiry, keys without one are left alone, needs redis 7
+	return s.client.ExpireGT(ctx, key, ttl).Result()
+}
+
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Get")
+	defer span.End()
+
+	// get value from redis
+	return s.client.Get(ctx, key).Result()
+}
+
+func (s *Store) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Set")
+	defer span.End()
+
+	// set value with expiry in redis
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *Store) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.SetNX")
+	defer span.End()
+
+	// set value only when the key does not exist yet
+	return s.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (s *Store) HGet(ctx context.Context, key, field string) (string, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.HGet")
+	defer span.End()
+
+	// get hash field from redis
+	return s.client.HGet(ctx, key, field).Result()
+}
+
+func (s *Store) Incr(ctx context.Context, key string, ttl time.Duration) (int64, time.Duration, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Incr")
+	defer span.End()
+
+	// count in a window that starts with the first increment, in one script so a counter never outlives it
+	result, err := incrScript.Run(ctx, s.client, []string{key}, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
+func (s *Store) Counts(ctx context.Context, keys ...string) ([]int64, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Counts")
+	defer span.End()
+
+	// read counters at once, missing keys count zero
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(values))
+	for i, value := range values {
+		if value, ok := value.(string); ok {
+			counts[i], _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return counts, nil
+}
+
+func (s *Store) Delete(ctx context.Context, keys ...string) error {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Delete")
+	defer span.End()
+
+	// delete keys from redis
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *Store) Window(ctx context.Context, key, member string, at time.Time, window time.Duration) ([]redis.Z, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Window")
+	defer span.End()
+
+	// drop members older than the window, return the rest and record member at its time
+	var members *redis.ZSliceCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", at.Add(-window).UnixMilli()))
+		members = pipe.ZRangeWithScores(ctx, key, 0, -1)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.UnixMilli()), Member: member})
+		pipe.PExpire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+func (s *Store) TouchMember(ctx context.Context, key, member string, at time.Time, ttl time.Duration) (bool, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.TouchMember")
+	defer span.End()
+
+	// score member by time and keep the set alive, reports whether member is new
+	var added *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		added = pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.UnixMilli()), Member: member})
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return added.Val() > 0, nil
+}
+
+func (s *Store) Members(ctx context.Context, key string) ([]redis.Z, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.Members")
+	defer span.End()
+
+	// members with scores, lowest first
+	return s.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+}
+
+func (s *Store) RemoveMembers(ctx context.Context, key string, members ...string) error {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.redis.RemoveMembers")
+	defer span.End()
+
+	// remove members from the sorted set
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return s.client.ZRem(ctx, key, values...).Err()
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *Store) Close() {
+	if s.client != nil {
+		_ = s.client.Close()
 	}
 }
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ovinc/zerotrust/internal/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	// register sql drivers
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+type SQLStore struct {
+	db     *sql.DB
+	driver string
+}
+
+func NewSQL(ctx context.Context, driver, dsn string) (*SQLStore, error) {
+	// open connection pool
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", driver, err)
+	}
+
+	// ping database to verify connectivity
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping %s database: %w", driver, err)
+	}
+
+	return &SQLStore{db: db, driver: driver}, nil
+}
+
+func (s *SQLStore) QueryValue(ctx context.Context, query string, args ...interface{}) (string, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.sql.QueryValue")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.system", s.driver), attribute.String("db.statement", query))
+
+	// read first column of first row
+	var value sql.NullString
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&value); err != nil {
+		return "", err
+	}
+	return value.String, nil
+}
+
+func (s *SQLStore) QueryValues(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	// start new span
+	ctx, span := otel.Tracer().Start(ctx, "store.sql.QueryValues")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.system", s.driver), attribute.String("db.statement", query))
+
+	// read first column of every row, nulls are skipped
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var values []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			values = append(values, value.String)
+		}
+	}
+	return values, rows.Err()
+}
+
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLStore) Close() {
+	if s.db != nil {
+		_ = s.db.Close()
+	}
+}
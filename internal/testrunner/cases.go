@@ -0,0 +1,55 @@
+package testrunner
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+type RequestCase struct {
+	Host       string            `yaml:"host"`
+	Path       string            `yaml:"path"`
+	Method     string            `yaml:"method"`
+	Headers    map[string]string `yaml:"headers"`
+	Cookie     string            `yaml:"cookie"`
+	RemoteAddr string            `yaml:"remote_addr"`
+}
+
+type SessionCase struct {
+	UserID     string              `yaml:"user_id"`
+	Backend    string              `yaml:"backend"`
+	Groups     []string            `yaml:"groups"`
+	Attributes map[string][]string `yaml:"attributes"`
+}
+
+type ExpectCase struct {
+	Status  int               `yaml:"status"`
+	Reason  *string           `yaml:"reason"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type Case struct {
+	Name    string       `yaml:"name"`
+	Request RequestCase  `yaml:"request"`
+	Session *SessionCase `yaml:"session"`
+	Expect  ExpectCase   `yaml:"expect"`
+}
+
+type Suite struct {
+	Cases []Case `yaml:"cases"`
+}
+
+func LoadSuite(path string) (*Suite, error) {
+	// read cases file from disk
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse yaml into suite struct
+	suite := &Suite{}
+	if err := yaml.Unmarshal(data, suite); err != nil {
+		return nil, err
+	}
+	return suite, nil
+}
@@ -0,0 +1,152 @@
+package testrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/handler"
+	"github.com/ovinc/zerotrust/internal/replay"
+	"github.com/ovinc/zerotrust/internal/session"
+)
+
+type Result struct {
+	Case     *Case
+	Decision *handler.Decision
+	Failures []string
+}
+
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+func Run(ctx context.Context, server *miniredis.Miniredis, suite *Suite) ([]*Result, error) {
+	results := make([]*Result, 0, len(suite.Cases))
+	for i := range suite.Cases {
+		result, err := runCase(ctx, server, i, &suite.Cases[i])
+		if err != nil {
+			return nil, fmt.Errorf("case %q: %w", suite.Cases[i].Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func runCase(ctx context.Context, server *miniredis.Miniredis, index int, c *Case) (*Result, error) {
+	application := app.Resolve(c.Request.Host)
+
+	// build forwarded request the way a proxy would send it
+	r := httptest.NewRequest(http.MethodGet, "/forward-auth", nil)
+	method := c.Request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	r.Header.Set("X-Forwarded-Method", method)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", c.Request.Host)
+	r.Header.Set("X-Forwarded-Uri", c.Request.Path)
+	for name, value := range c.Request.Headers {
+		r.Header.Set(name, value)
+	}
+	if c.Request.RemoteAddr != "" {
+		r.RemoteAddr = net.JoinHostPort(c.Request.RemoteAddr, "1234")
+	}
+
+	// every case starts from an empty session store
+	server.FlushAll()
+
+	// store fake session under the cookie value
+	cookie := c.Request.Cookie
+	if c.Session != nil {
+		if cookie == "" {
+			cookie = fmt.Sprintf("test-session-%d", index)
+		}
+		values, err := sessionValues(application, c.Session)
+		if err != nil {
+			return nil, err
+		}
+		if err := replay.SeedSession(server, application, cookie, values); err != nil {
+			return nil, err
+		}
+	}
+	if cookie != "" {
+		r.AddCookie(&http.Cookie{Name: application.Auth.SessionCookieName, Value: cookie})
+	}
+
+	// run the real decision path
+	decision := handler.Authorize(ctx, handler.NewForwardAuthRequest(r))
+	return &Result{Case: c, Decision: decision, Failures: compare(&c.Expect, decision)}, nil
+}
+
+func sessionValues(application *app.Application, s *SessionCase) (map[string]interface{}, error) {
+	values := map[string]interface{}{"_auth_user_id": s.UserID}
+	if s.Backend != "" {
+		values["_auth_user_backend"] = s.Backend
+	}
+
+	// attributes are stored under their configured session keys
+	keys := application.Auth.SessionAttributes
+	attributes := map[string][]string{}
+	for name, value := range s.Attributes {
+		attributes[name] = value
+	}
+	if len(s.Groups) > 0 {
+		attributes[session.AttributeGroups] = s.Groups
+	}
+	for name, value := range attributes {
+		key, ok := keys[name]
+		if !ok {
+			return nil, fmt.Errorf("attribute %q has no session key in auth.session_attributes", name)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+func compare(expect *ExpectCase, decision *handler.Decision) []string {
+	var failures []string
+	if expect.Status != 0 && expect.Status != decision.Status {
+		failures = append(failures, fmt.Sprintf("status: expected %d, got %d", expect.Status, decision.Status))
+	}
+	if expect.Reason != nil && *expect.Reason != decision.Reason {
+		failures = append(failures, fmt.Sprintf("reason: expected %q, got %q", *expect.Reason, decision.Reason))
+	}
+	for name, value := range expect.Headers {
+		if got := decision.Headers.Get(name); got != value {
+			failures = append(failures, fmt.Sprintf("header %s: expected %q, got %q", name, value, got))
+		}
+	}
+	return failures
+}
+
+func Print(w io.Writer, results []*Result) (failed int) {
+	for _, r := range results {
+		// matched rule helps to understand why a case passed or failed
+		rule := "-"
+		if r.Decision.Policy != nil && r.Decision.Policy.Rule != "" {
+			rule = r.Decision.Policy.Rule
+		}
+		status := "PASS"
+		if !r.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		authenticator := "-"
+		if r.Decision.Authenticator != "" {
+			authenticator = r.Decision.Authenticator
+		}
+		_, _ = fmt.Fprintf(w, "%s  %s  (status %d, reason %q, authenticator %s, rule %s)\n",
+			status, r.Case.Name, r.Decision.Status, r.Decision.Reason, authenticator, rule)
+		for _, f := range r.Failures {
+			_, _ = fmt.Fprintf(w, "    %s\n", f)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%d passed, %d failed\n", len(results)-failed, failed)
+	return failed
+}
@@ -0,0 +1,117 @@
+package usersource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/ovinc/zerotrust/internal/app"
+	"github.com/ovinc/zerotrust/internal/config"
+	"github.com/ovinc/zerotrust/internal/otel"
+	"github.com/ovinc/zerotrust/internal/store"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrNotInitialized = errors.New("user source is not initialized")
+)
+
+var source *store.SQLStore
+
+func Init() {
+	ctx := context.Background()
+	cfg := config.Get().UserSource
+	if !cfg.Enabled {
+		return
+	}
+
+	// connect to the django database
+	var err error
+	source, err = store.NewSQL(ctx, cfg.Driver, cfg.DSN)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Fatal("failed to initialize user source")
+	}
+}
+
+func Close() {
+	if source != nil {
+		source.Close()
+	}
+}
+
+func Enabled() bool {
+	return config.Get().UserSource.Enabled
+}
+
+func LastLogin(ctx context.Context, userID string, useCache bool) (string, bool, error) {
+	// start span
+	ctx, span := otel.Tracer().Start(ctx, "usersource.LastLogin")
+	defer span.End()
+
+	cfg := config.Get().UserSource
+	cache := app.Default().Store
+	cacheKey := cacheKey(&cfg, userID)
+
+	// cache first unless the caller needs a fresh value, errors fall through to the database
+	if cfg.CacheTTL > 0 && useCache {
+		if value, err := cache.Get(ctx, cacheKey); err == nil {
+			return value, true, nil
+		} else if !errors.Is(err, redis.Nil) {
+			logrus.WithContext(ctx).WithError(err).Warn("[UserSource] failed to read last login cache")
+		}
+	}
+
+	// query user table
+	if source == nil {
+		return "", false, ErrNotInitialized
+	}
+	value, err := source.QueryValue(ctx, query(&cfg), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, ErrUserNotFound
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	// fill cache
+	if cfg.CacheTTL > 0 {
+		if err := cache.Set(ctx, cacheKey, value, cfg.CacheTTL); err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("[UserSource] failed to write last login cache")
+		}
+	}
+	return value, false, nil
+}
+
+func Values(ctx context.Context, query, userID string) ([]string, error) {
+	// start span
+	ctx, span := otel.Tracer().Start(ctx, "usersource.Values")
+	defer span.End()
+
+	// profile data such as tenant memberships, one value per row
+	if source == nil {
+		return nil, ErrNotInitialized
+	}
+	return source.QueryValues(ctx, query, userID)
+}
+
+func query(cfg *config.UserSourceConfig) string {
+	if cfg.Query != "" {
+		return cfg.Query
+	}
+	// default django table as unix seconds with the driver specific placeholder
+	if cfg.Driver == "mysql" {
+		return "SELECT UNIX_TIMESTAMP(last_login) FROM auth_user WHERE id = ?"
+	}
+	return "SELECT EXTRACT(EPOCH FROM last_login) FROM auth_user WHERE id = $1"
+}
+
+func cacheKey(cfg *config.UserSourceConfig, userID string) string {
+	format := cfg.CacheKeyFormat
+	if format == "" {
+		format = "zerotrust:last_login:{user_id}"
+	}
+	return strings.Replace(format, "{user_id}", userID, 1)
+}